@@ -0,0 +1,267 @@
+package ibgo
+
+import (
+	"time"
+)
+
+// Kinds of bars an Aggregator builds
+const (
+	TimeBars = iota
+	TickBars
+	VolumeBars
+	DollarBars
+)
+
+// BarCloseDelay is how long past its end a streamed time bar waits for late ticks before it is closed
+var BarCloseDelay = time.Second
+
+// Aggregator builds bars from ordered ticks. Time bars are aligned to the start of their trading session and
+// no bar runs over a session end; between sessions they count back from the start of the next one. Sessions
+// come from the instrument's calendar on every day, so alignment is the same inside and outside the span IB
+// published hours for. Only when IB published no hours at all are time bars aligned to midnight in the
+// instrument's time zone and useRTH drops nothing.
+// Trades are taken at Last and Size, quotes at their midpoint without size, so volume and dollar bars need trades.
+// An Aggregator is not safe for concurrent use
+type Aggregator struct {
+	kind      int
+	interval  time.Duration
+	threshold float64
+	useRTH    bool
+	cal       *calendar
+	loc       *time.Location
+	open      bool
+	bar       BarData
+	end       time.Time
+	filled    float64
+	pv        float64
+}
+
+func (ins *Instrument) newAggregator(kind int, useRTH bool) *Aggregator {
+	a := &Aggregator{kind: kind, useRTH: useRTH, cal: ins.calendar(useRTH)}
+	loc, err := time.LoadLocation(ins.Detail.TimeZoneID)
+	if err != nil {
+		loc = time.UTC
+	}
+	a.loc = loc
+	return a
+}
+
+// NewTimeAggregator builds bars lasting d, such as 1 second or 7 minutes. With useRTH ticks outside liquid hours are dropped
+func (ins *Instrument) NewTimeAggregator(d time.Duration, useRTH bool) *Aggregator {
+	a := ins.newAggregator(TimeBars, useRTH)
+	a.interval = d
+	return a
+}
+
+// NewTickAggregator closes a bar every n ticks
+func (ins *Instrument) NewTickAggregator(n int64, useRTH bool) *Aggregator {
+	a := ins.newAggregator(TickBars, useRTH)
+	a.threshold = float64(n)
+	return a
+}
+
+// NewVolumeAggregator closes a bar once it has traded volume. The trade crossing the threshold stays whole in its bar
+func (ins *Instrument) NewVolumeAggregator(volume int64, useRTH bool) *Aggregator {
+	a := ins.newAggregator(VolumeBars, useRTH)
+	a.threshold = float64(volume)
+	return a
+}
+
+// NewDollarAggregator closes a bar once it has traded value, price times size
+func (ins *Instrument) NewDollarAggregator(value float64, useRTH bool) *Aggregator {
+	a := ins.newAggregator(DollarBars, useRTH)
+	a.threshold = value
+	return a
+}
+
+func tickPrice(t *Tick) (price float64, size int64) {
+	switch {
+	case t.Last != 0:
+		return t.Last, t.Size
+	case t.Midpoint != 0:
+		return t.Midpoint, 0
+	case t.Bid != 0 && t.Ask != 0:
+		return (t.Bid + t.Ask) / 2, 0
+	}
+	return 0, 0
+}
+
+// Add feeds t and returns the bars it closes
+func (a *Aggregator) Add(t Tick) (closed []BarData) {
+	price, size := tickPrice(&t)
+	if price == 0 {
+		return
+	}
+	var session *Session
+	if a.cal != nil {
+		s, in := a.cal.find(t.Time)
+		if in {
+			session = &s
+		} else if a.useRTH {
+			return
+		}
+	}
+	if a.open && !a.end.IsZero() && !t.Time.Before(a.end) {
+		closed = append(closed, a.close())
+	}
+	if !a.open {
+		a.start(t.Time, session, price)
+	}
+	bar := &a.bar
+	if price > bar.High {
+		bar.High = price
+	}
+	if price < bar.Low {
+		bar.Low = price
+	}
+	bar.Close = price
+	bar.Volume += size
+	bar.TradeCount++
+	a.pv += price * float64(size)
+	switch a.kind {
+	case TickBars:
+		a.filled++
+	case VolumeBars:
+		a.filled += float64(size)
+	case DollarBars:
+		a.filled += price * float64(size)
+	}
+	if a.kind != TimeBars && a.filled >= a.threshold {
+		closed = append(closed, a.close())
+	}
+	return
+}
+
+func (a *Aggregator) start(t time.Time, session *Session, price float64) {
+	a.open = true
+	a.bar = BarData{Time: t, Open: price, High: price, Low: price}
+	a.end = time.Time{}
+	if session != nil {
+		a.end = session.End
+	}
+	if a.kind == TimeBars {
+		a.bar.Time, a.end = sessionBucket(a.cal, session, a.loc, t, a.interval)
+	}
+}
+
+// sessionBucket returns the interval of length d holding t, which lies in session when it is not nil.
+// Between sessions of c intervals are counted back from the next session start and end at it
+func sessionBucket(c *calendar, session *Session, loc *time.Location, t time.Time, d time.Duration) (start time.Time, end time.Time) {
+	if session != nil || c == nil {
+		return timeBucket(session, loc, t, d)
+	}
+	next, ok := c.next(t)
+	if !ok {
+		return timeBucket(nil, loc, t, d)
+	}
+	start = next.Start.Add(-(next.Start.Sub(t) + d - 1) / d * d)
+	return start, start.Add(d)
+}
+
+// timeBucket returns the interval of length d holding t, aligned to the start of session and cut short at its end.
+// Without a session it is aligned to midnight in loc
+func timeBucket(session *Session, loc *time.Location, t time.Time, d time.Duration) (start time.Time, end time.Time) {
+	y, m, day := t.In(loc).Date()
+	anchor := time.Date(y, m, day, 0, 0, 0, 0, loc)
+	if session != nil {
+		anchor = session.Start
+	}
+	start = anchor.Add(t.Sub(anchor) / d * d)
+	end = start.Add(d)
+	if session != nil && session.End.Before(end) {
+		end = session.End
+	}
+	return
+}
+
+func (a *Aggregator) close() BarData {
+	bar := a.bar
+	if bar.Volume > 0 {
+		bar.WAP = a.pv / float64(bar.Volume)
+	}
+	a.open, a.filled, a.pv = false, 0, 0
+	return bar
+}
+
+// Flush closes the bar in progress
+func (a *Aggregator) Flush() (bar BarData, ok bool) {
+	if !a.open {
+		return
+	}
+	return a.close(), true
+}
+
+// Bars aggregates historical ticks, the last bar included even when incomplete
+func (a *Aggregator) Bars(ticks []Tick) (bars []BarData) {
+	bars = make([]BarData, 0)
+	for _, t := range ticks {
+		bars = append(bars, a.Add(t)...)
+	}
+	if bar, ok := a.Flush(); ok {
+		bars = append(bars, bar)
+	}
+	return
+}
+
+type BarStream struct {
+	Bars   chan BarData
+	Cancel func() error
+	Err    error
+}
+
+// Stream aggregates a live tick stream. Time bars close by the clock BarCloseDelay after their end,
+// other bars on the tick that completes them. Cancel cancels the tick stream
+func (a *Aggregator) Stream(stream *TickStream) *BarStream {
+	bs := &BarStream{make(chan BarData), stream.Cancel, nil}
+	go func() {
+		pending := make([]BarData, 0)
+		var first BarData
+		ticks := stream.Ticks
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		<-timer.C
+		running := false
+		var armed time.Time
+		for {
+			var update chan BarData
+			if len(pending) > 0 {
+				first = pending[0]
+				update = bs.Bars
+			} else if ticks == nil {
+				close(bs.Bars)
+				return
+			}
+			var fire <-chan time.Time
+			if a.kind == TimeBars && a.open {
+				if !a.end.Equal(armed) {
+					if running && !timer.Stop() {
+						<-timer.C
+					}
+					timer.Reset(time.Until(a.end) + BarCloseDelay)
+					running, armed = true, a.end
+				}
+				fire = timer.C
+			}
+			select {
+			case t, ok := <-ticks:
+				if !ok {
+					if bar, ok := a.Flush(); ok {
+						pending = append(pending, bar)
+					}
+					bs.Err = stream.Err
+					ticks = nil
+					continue
+				}
+				pending = append(pending, a.Add(t)...)
+			case <-fire:
+				running, armed = false, time.Time{}
+				if bar, ok := a.Flush(); ok {
+					pending = append(pending, bar)
+				}
+			case update <- first:
+				pending = pending[1:]
+			}
+		}
+	}()
+	return bs
+}
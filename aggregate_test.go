@@ -0,0 +1,99 @@
+package ibgo
+
+import (
+	"testing"
+	"time"
+)
+
+// weekInstrument trades 09:30 to 16:00 New York time and has hours published for the week of 8 January 2024
+func weekInstrument(t *testing.T) (*Instrument, *time.Location) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	ins := &Instrument{}
+	ins.Detail.TimeZoneID = "America/New_York"
+	for day := 8; day <= 12; day++ {
+		s := Session{time.Date(2024, 1, day, 9, 30, 0, 0, loc), time.Date(2024, 1, day, 16, 0, 0, 0, loc)}
+		ins.Detail.TradingHours = append(ins.Detail.TradingHours, s)
+		ins.Detail.LiquidHours = append(ins.Detail.LiquidHours, s)
+	}
+	return ins, loc
+}
+
+func TestTimeBarAlignment(t *testing.T) {
+	ins, loc := weekInstrument(t)
+	at := func(day, h, m int) time.Time { return time.Date(2024, 1, day, h, m, 0, 0, loc) }
+	tests := []struct {
+		tick       time.Time
+		start, end time.Time
+	}{
+		// within the published week
+		{at(9, 9, 47), at(9, 9, 44), at(9, 9, 51)},
+		{at(9, 15, 59), at(9, 15, 55), at(9, 16, 0)},
+		// weeks before it, from the weekly pattern
+		{at(2, 9, 47), at(2, 9, 44), at(2, 9, 51)},
+		{at(2, 15, 59), at(2, 15, 55), at(2, 16, 0)},
+		// between sessions, counted back from the next open
+		{at(2, 17, 0), at(2, 16, 56), at(2, 17, 3)},
+		{at(3, 9, 23), at(3, 9, 23), at(3, 9, 30)},
+	}
+	for _, tt := range tests {
+		a := ins.NewTimeAggregator(time.Minute*7, false)
+		a.Add(Tick{Time: tt.tick, Last: 1, Size: 1})
+		if !a.bar.Time.Equal(tt.start) || !a.end.Equal(tt.end) {
+			t.Errorf("tick at %v: bar %v to %v, want %v to %v", tt.tick, a.bar.Time, a.end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestTimeBarsWithoutHours(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	ins := &Instrument{}
+	ins.Detail.TimeZoneID = "America/New_York"
+	a := ins.NewTimeAggregator(time.Minute*7, true)
+	bars := a.Bars([]Tick{{Time: time.Date(2024, 1, 2, 9, 47, 0, 0, loc), Last: 1, Size: 1}})
+	if want := time.Date(2024, 1, 2, 9, 41, 0, 0, loc); len(bars) != 1 || !bars[0].Time.Equal(want) {
+		t.Errorf("bars %v, want one at %v", bars, want)
+	}
+}
+
+func TestRTHDropsTicksOutsideSessions(t *testing.T) {
+	ins, loc := weekInstrument(t)
+	a := ins.NewTickAggregator(1, true)
+	ticks := []Tick{
+		{Time: time.Date(2024, 1, 2, 8, 0, 0, 0, loc), Last: 1, Size: 1},
+		{Time: time.Date(2024, 1, 2, 10, 0, 0, 0, loc), Last: 2, Size: 1},
+		{Time: time.Date(2024, 1, 9, 17, 0, 0, 0, loc), Last: 3, Size: 1},
+	}
+	if bars := a.Bars(ticks); len(bars) != 1 || bars[0].Close != 2 {
+		t.Errorf("bars %v, want only the one in hours", bars)
+	}
+}
+
+func TestStreamClosesTimeBarsByClock(t *testing.T) {
+	prev := BarCloseDelay
+	BarCloseDelay = time.Millisecond * 10
+	defer func() { BarCloseDelay = prev }()
+	ins := &Instrument{}
+	a := ins.NewTimeAggregator(time.Millisecond*50, false)
+	ticks := make(chan Tick)
+	bs := a.Stream(&TickStream{Ticks: ticks})
+	go func() {
+		ticks <- Tick{Time: time.Now(), Last: 1, Size: 1}
+	}()
+	select {
+	case bar := <-bs.Bars:
+		if bar.Close != 1 {
+			t.Errorf("bar %v", bar)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("time bar was not closed by the clock")
+	}
+	close(ticks)
+	for range bs.Bars {
+	}
+}
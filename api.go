@@ -4,10 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 var ErrDisconnected = errors.New("Client disconnected")
+var ErrReadonly = errors.New("Client is readonly")
+var ErrSubscribed = errors.New("Already subscribed")
+var ErrNoReply = errors.New("No reply")
+
+// ReplyTimeout is how long requests IB may leave unanswered, such as a market rule of an unknown id, wait for a reply
+var ReplyTimeout = time.Second * 10
 
 func (c *IBClient) reqStatic(key string) (ack chan struct{}, respCh chan *Message, err error) {
 	if c.status < 3 {
@@ -64,6 +72,19 @@ func (c *IBClient) reqOrder() (id string, ack chan struct{}, respCh chan *Messag
 	}
 }
 
+// unregister stops routing key to respCh. Messages still in flight are read off so the receiver never blocks
+func (c *IBClient) unregister(key string, respCh chan *Message) {
+	for {
+		select {
+		case c.unRegisterChan <- key:
+			for range respCh {
+			}
+			return
+		case <-respCh:
+		}
+	}
+}
+
 func (c *IBClient) reqCancel(code string, version string, id string) (err error) {
 	if c.status < 3 {
 		err = ErrDisconnected
@@ -82,7 +103,9 @@ func (c *IBClient) reqCancel(code string, version string, id string) (err error)
 	if version != "" {
 		c.writer.writeString(version)
 	}
-	c.writer.writeString(id)
+	if id != "" {
+		c.writer.writeString(id)
+	}
 	err = c.writer.send()
 	return
 }
@@ -104,7 +127,14 @@ func (c *IBClient) ReqCurrentTime() (t time.Time, err error) {
 	return
 }
 
+// ReqContractDetails answers from the contract cache when one is in use. A failure to save the cache is returned
+// along with the details
 func (c *IBClient) ReqContractDetails(con Contract) (response []ContractData, err error) {
+	if c.contractCache != nil {
+		if cached, ok := c.contractCache.Get(con); ok {
+			return cached, nil
+		}
+	}
 	id, ack, respCh, err := c.reqTicker()
 	if err != nil {
 		return
@@ -119,6 +149,41 @@ func (c *IBClient) ReqContractDetails(con Contract) (response []ContractData, er
 		return
 	}
 	response = make([]ContractData, 0)
+	for msg := range respCh {
+		if msg.code[0] == 'E' {
+			err = fmt.Errorf("Error[%v]:%v", msg.code, msg.body)
+			return
+		}
+		if msg.code == inCONTRACTDATAEND {
+			c.cachePut(con, response)
+			return
+		}
+		switch data := (msg.body).(type) {
+		case *ContractData:
+			response = append(response, *data)
+		case *BondContractData:
+			response = append(response, ContractData{data.Contract, data.ContractDetail})
+		}
+	}
+	return
+}
+
+func (c *IBClient) ReqBondContractDetails(con Contract) (response []BondContractData, err error) {
+	id, ack, respCh, err := c.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	con.SecType = "BOND"
+	c.writer.writeString(outREQCONTRACTDATA)
+	c.writer.writeString("8")
+	c.writer.writeString(id)
+	c.writer.writeContractWithFull(&con)
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	response = make([]BondContractData, 0)
 	for msg := range respCh {
 		if msg.code[0] == 'E' {
 			err = fmt.Errorf("Error[%v]:%v", msg.code, msg.body)
@@ -127,13 +192,19 @@ func (c *IBClient) ReqContractDetails(con Contract) (response []ContractData, er
 		if msg.code == inCONTRACTDATAEND {
 			return
 		}
-		data := (msg.body).(*ContractData)
-		response = append(response, *data)
+		if data, ok := (msg.body).(*BondContractData); ok {
+			response = append(response, *data)
+		}
 	}
 	return
 }
 
-func (c *IBClient) ReqHistoricalData(con *Contract, endDateTime string, durationStr string, barSizeSetting string, whatToShow string, useRTH bool, formatDate int64, keepUpToDate bool) (bars []BarData, err error) {
+// ReqHistoricalData returns the bars of con. With formatDate 1 IB sends dates and date times without a zone,
+// in the zone TWS is logged in with; they are read in loc, UTC when nil. Epoch times of formatDate 2 need no zone
+func (c *IBClient) ReqHistoricalData(con *Contract, endDateTime string, durationStr Duration, barSizeSetting BarSize, whatToShow WhatToShow, useRTH bool, formatDate int64, keepUpToDate bool, loc *time.Location) (bars []BarData, err error) {
+	if err = ValidateBarRequest(con.SecType, durationStr, barSizeSetting, whatToShow); err != nil {
+		return
+	}
 	id, ack, respCh, err := c.reqTicker()
 	if err != nil {
 		return
@@ -145,16 +216,506 @@ func (c *IBClient) ReqHistoricalData(con *Contract, endDateTime string, duration
 	c.writer.writeString(id)
 	c.writer.writeContractWithExpired(con)
 	c.writer.writeString(endDateTime)
-	c.writer.writeString(barSizeSetting)
-	c.writer.writeString(durationStr)
+	c.writer.writeString(string(barSizeSetting))
+	c.writer.writeString(string(durationStr))
 	c.writer.writeBool(useRTH)
-	c.writer.writeString(whatToShow)
+	c.writer.writeString(string(whatToShow))
 	c.writer.writeInt(formatDate)
-	//TODO BAGS
+	c.writer.writeComboLegs(con)
 	c.writer.writeBool(keepUpToDate) // serverVersion 124
 	c.writer.writeString("")
 	c.writer.send()
 	msg := <-respCh
-	bars = (msg.body).(*HistoricalData).Bars
+	if msg.code[:1] == "E" {
+		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+		return
+	}
+	data := (msg.body).(*HistoricalData)
+	if loc == nil {
+		loc = time.UTC
+	}
+	if err = data.parseTimes(loc); err != nil {
+		return
+	}
+	bars = data.Bars
+	return
+}
+
+func (c *IBClient) ReqNewsProviders() (providers []NewsProvider, err error) {
+	ack, respCh, err := c.reqStatic(inNEWSPROVIDERS)
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outREQNEWSPROVIDERS)
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	providers = (msg.body).([]NewsProvider)
+	return
+}
+
+func (c *IBClient) ReqNewsArticle(providerCode string, articleID string) (article *NewsArticle, err error) {
+	id, ack, respCh, err := c.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outREQNEWSARTICLE)
+	c.writer.writeString(id)
+	c.writer.writeString(providerCode)
+	c.writer.writeString(articleID)
+	c.writer.writeString("") // serverVersion 128
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	if msg.code[:1] == "E" {
+		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+		return
+	}
+	article = (msg.body).(*NewsArticle)
+	return
+}
+
+type BulletinStream struct {
+	Bulletins chan NewsBulletin
+	Cancel    func() error
+	Err       error
+}
+
+// ReqNewsBulletins subscribes to IB news bulletins. Only one subscription can be open at a time
+func (c *IBClient) ReqNewsBulletins(allMsgs bool) (stream *BulletinStream, err error) {
+	c.cacheMu.Lock()
+	if c.bulletinsOn {
+		c.cacheMu.Unlock()
+		return nil, ErrSubscribed
+	}
+	c.bulletinsOn = true
+	c.cacheMu.Unlock()
+	defer func() {
+		if err != nil {
+			c.cacheMu.Lock()
+			c.bulletinsOn = false
+			c.cacheMu.Unlock()
+		}
+	}()
+	ack, respCh, err := c.reqStatic(inNEWSBULLETINS)
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outREQNEWSBULLETINS)
+	c.writer.writeString("1")
+	c.writer.writeBool(allMsgs)
+	err = c.writer.send()
+	if err != nil {
+		go c.unregister("*"+inNEWSBULLETINS, respCh)
+		return
+	}
+	stream = &BulletinStream{make(chan NewsBulletin), nil, nil}
+	done := make(chan struct{})
+	var once sync.Once
+	stream.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return c.reqCancel(outCANCELNEWSBULLETINS, "1", "")
+	}
+	go func() {
+		defer func() {
+			close(stream.Bulletins)
+			c.unregister("*"+inNEWSBULLETINS, respCh)
+			c.cacheMu.Lock()
+			c.bulletinsOn = false
+			c.cacheMu.Unlock()
+		}()
+		pending := make([]NewsBulletin, 0)
+		var first NewsBulletin
+		var update chan NewsBulletin
+		for {
+			if len(pending) > 0 {
+				first = pending[0]
+				update = stream.Bulletins
+			}
+			select {
+			case msg := <-respCh:
+				if msg.code[:1] == "E" {
+					stream.Err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+					return
+				}
+				pending = append(pending, *(msg.body).(*NewsBulletin))
+			case update <- first:
+				pending = pending[1:]
+				update = nil
+			case <-done:
+				return
+			}
+		}
+	}()
+	return
+}
+
+// flight is a request shared by every caller asking for the same key while it is in progress
+type flight struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// share runs fn once for the concurrent callers of key. cacheMu is only held to look up and register the flight
+func (c *IBClient) share(key string, fn func() (interface{}, error)) (interface{}, error) {
+	c.cacheMu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.cacheMu.Unlock()
+		<-f.done
+		return f.val, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	if c.flights == nil {
+		c.flights = make(map[string]*flight)
+	}
+	c.flights[key] = f
+	c.cacheMu.Unlock()
+	f.val, f.err = fn()
+	c.cacheMu.Lock()
+	delete(c.flights, key)
+	c.cacheMu.Unlock()
+	close(f.done)
+	return f.val, f.err
+}
+
+// ReqMarketRule returns the price increment ladder of rule id. Rules are cached for the life of the client.
+// IB does not route the error of an unknown id back, so ErrNoReply is returned after ReplyTimeout
+func (c *IBClient) ReqMarketRule(id int64) (*MarketRule, error) {
+	c.cacheMu.Lock()
+	rule, ok := c.marketRules[id]
+	c.cacheMu.Unlock()
+	if ok {
+		return rule, nil
+	}
+	v, err := c.share("rule"+strconv.FormatInt(id, 10), func() (interface{}, error) {
+		rule, err := c.reqMarketRule(id)
+		if err != nil {
+			return nil, err
+		}
+		c.cacheMu.Lock()
+		if c.marketRules == nil {
+			c.marketRules = make(map[int64]*MarketRule)
+		}
+		c.marketRules[id] = rule
+		c.cacheMu.Unlock()
+		return rule, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*MarketRule), nil
+}
+
+func (c *IBClient) reqMarketRule(id int64) (rule *MarketRule, err error) {
+	key := inMARKETRULE + strconv.FormatInt(id, 10)
+	ack, respCh, err := c.reqStatic(key)
+	if err != nil {
+		return
+	}
+	c.writer.writeString(outREQMARKETRULE)
+	c.writer.writeInt(id)
+	err = c.writer.send()
+	close(ack)
+	if err != nil {
+		go c.unregister("*"+key, respCh)
+		return
+	}
+	select {
+	case msg := <-respCh:
+		rule = (msg.body).(*MarketRule)
+	case <-time.After(ReplyTimeout):
+		go c.unregister("*"+key, respCh)
+		err = ErrNoReply
+	}
+	return
+}
+
+// ReqSmartComponents returns the exchanges behind a BBO exchange map. Results are cached for the life of the client
+func (c *IBClient) ReqSmartComponents(bboExchange string) ([]SmartComponent, error) {
+	c.cacheMu.Lock()
+	components, ok := c.smartComps[bboExchange]
+	c.cacheMu.Unlock()
+	if ok {
+		return components, nil
+	}
+	v, err := c.share("smart"+bboExchange, func() (interface{}, error) {
+		components, err := c.reqSmartComponents(bboExchange)
+		if err != nil {
+			return nil, err
+		}
+		c.cacheMu.Lock()
+		if c.smartComps == nil {
+			c.smartComps = make(map[string][]SmartComponent)
+		}
+		c.smartComps[bboExchange] = components
+		c.cacheMu.Unlock()
+		return components, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]SmartComponent), nil
+}
+
+func (c *IBClient) reqSmartComponents(bboExchange string) (components []SmartComponent, err error) {
+	id, ack, respCh, err := c.reqTicker()
+	if err != nil {
+		return
+	}
+	c.writer.writeString(outREQSMARTCOMPONENTS)
+	c.writer.writeString(id)
+	c.writer.writeString(bboExchange)
+	err = c.writer.send()
+	close(ack)
+	if err != nil {
+		go c.unregister(id, respCh)
+		return
+	}
+	select {
+	case msg := <-respCh:
+		if msg.code[:1] == "E" {
+			err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+			return
+		}
+		components = (msg.body).([]SmartComponent)
+	case <-time.After(ReplyTimeout):
+		go c.unregister(id, respCh)
+		err = ErrNoReply
+	}
+	return
+}
+
+func (c *IBClient) ReqFamilyCodes() (codes []FamilyCode, err error) {
+	ack, respCh, err := c.reqStatic(inFAMILYCODES)
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outREQFAMILYCODES)
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	codes = (msg.body).([]FamilyCode)
+	return
+}
+
+func (c *IBClient) ReqSoftDollarTiers() (tiers []SoftDollarTier, err error) {
+	id, ack, respCh, err := c.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outREQSOFTDOLLARTIERS)
+	c.writer.writeString(id)
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	if msg.code[:1] == "E" {
+		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+		return
+	}
+	tiers = (msg.body).([]SoftDollarTier)
+	return
+}
+
+// ReqFA returns the raw financial advisor configuration XML of faDataType FAGroups, FAProfiles or FAAliases
+func (c *IBClient) ReqFA(faDataType int64) (str string, err error) {
+	ack, respCh, err := c.reqStatic(inRECEIVEFA + strconv.FormatInt(faDataType, 10))
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outREQFA)
+	c.writer.writeString("1")
+	c.writer.writeInt(faDataType)
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	str = (msg.body).(*ReceiveFA).XML
+	return
+}
+
+// ReplaceFA overwrites the financial advisor configuration of faDataType with the given XML
+func (c *IBClient) ReplaceFA(faDataType int64, str string) (err error) {
+	if c.readonly {
+		return ErrReadonly
+	}
+	if c.serverVersion < vMINSERVERVERREPLACEFAEND {
+		ack, _, err := c.reqStatic("")
+		if err != nil {
+			return err
+		}
+		defer close(ack)
+		c.writer.writeString(outREPLACEFA)
+		c.writer.writeString("1")
+		c.writer.writeInt(faDataType)
+		c.writer.writeString(str)
+		return c.writer.send()
+	}
+	id, ack, respCh, err := c.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outREPLACEFA)
+	c.writer.writeString("1")
+	c.writer.writeInt(faDataType)
+	c.writer.writeString(str)
+	c.writer.writeString(id)
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	if msg.code[:1] == "E" {
+		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+	}
+	return
+}
+
+func (c *IBClient) QueryDisplayGroups() (groups []int64, err error) {
+	id, ack, respCh, err := c.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outQUERYDISPLAYGROUPS)
+	c.writer.writeString("1")
+	c.writer.writeString(id)
+	err = c.writer.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	if msg.code[:1] == "E" {
+		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+		return
+	}
+	groups = make([]int64, 0)
+	for _, str := range strings.Split((msg.body).(string), "|") {
+		if g, err := strconv.ParseInt(str, 10, 64); err == nil {
+			groups = append(groups, g)
+		}
+	}
+	return
+}
+
+// DisplayGroup follows a TWS window group. A nil Instrument is sent when the group shows nothing
+// DisplayGroup ends with Instruments closed, after Cancel or with the error in Err. A contract of the group
+// that fails to resolve ends it as well
+type DisplayGroup struct {
+	Instruments chan *Instrument
+	Update      func(ins *Instrument) error
+	Cancel      func() error
+	Err         error
+}
+
+func (c *IBClient) SubscribeToGroupEvents(groupID int64) (group *DisplayGroup, err error) {
+	id, ack, respCh, err := c.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	c.writer.writeString(outSUBSCRIBETOGROUPEVENTS)
+	c.writer.writeString("1")
+	c.writer.writeString(id)
+	c.writer.writeInt(groupID)
+	err = c.writer.send()
+	if err != nil {
+		go c.unregister(id, respCh)
+		return
+	}
+	group = &DisplayGroup{make(chan *Instrument), nil, nil, nil}
+	group.Update = func(ins *Instrument) error {
+		if ins == nil {
+			return errors.New("No instrument to update display group with")
+		}
+		ack, _, err := c.reqStatic("")
+		if err != nil {
+			return err
+		}
+		defer close(ack)
+		c.writer.writeString(outUPDATEDISPLAYGROUP)
+		c.writer.writeString("1")
+		c.writer.writeString(id)
+		c.writer.writeString(fmt.Sprintf("%d@%s", ins.ConID(), ins.contract.Exchange))
+		return c.writer.send()
+	}
+	done := make(chan struct{})
+	var once sync.Once
+	group.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return c.reqCancel(outUNSUBSCRIBEFROMGROUPEVENTS, "1", id)
+	}
+	infos := make(chan string)
+	var msgErr error
+	go func() {
+		defer func() {
+			close(infos)
+			c.unregister(id, respCh)
+		}()
+		pending := make([]string, 0)
+		var first string
+		var update chan string
+		for {
+			if len(pending) > 0 {
+				first = pending[0]
+				update = infos
+			}
+			select {
+			case msg := <-respCh:
+				if msg.code[:1] == "E" {
+					msgErr = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+					return
+				}
+				pending = append(pending, (msg.body).(string))
+			case update <- first:
+				pending = pending[1:]
+				update = nil
+			case <-done:
+				return
+			}
+		}
+	}()
+	// instruments are resolved apart from the message loop so that contract detail responses are never blocked
+	go func() {
+		defer close(group.Instruments)
+		for info := range infos {
+			var ins *Instrument
+			sl := strings.Split(info, "@")
+			if conID, err := strconv.ParseInt(sl[0], 10, 64); err == nil {
+				con := Contract{ConID: conID}
+				if len(sl) > 1 {
+					con.Exchange = sl[1]
+				}
+				if ins, err = c.NewInstrument(con); err != nil {
+					group.Err = fmt.Errorf("Failed to resolve display group contract %v: %v", info, err)
+					group.Cancel()
+					for range infos {
+					}
+					return
+				}
+			}
+			select {
+			case group.Instruments <- ins:
+			case <-done:
+			}
+		}
+		group.Err = msgErr
+	}()
 	return
 }
@@ -0,0 +1,116 @@
+// Package backtest replays historical ticks and bars through the stream types live data arrives in
+// and runs a strategy against a simulated broker
+package backtest
+
+import (
+	"sync"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// Strategy reacts to market data by trading through a Broker. Orders it places are matched from the
+// next tick or bar on, so it never trades on data it has not seen
+type Strategy interface {
+	OnTick(t ibgo.Tick, broker Broker)
+	OnBar(bar ibgo.BarData, broker Broker)
+}
+
+// Engine drives Strategy with a stream, matching the orders of Broker before each tick or bar reaches it.
+// Streams can come from Replay, ReplayBars or, to paper trade, from live data
+type Engine struct {
+	Strategy Strategy
+	Broker   *SimBroker
+}
+
+// Result is the trade log and equity curve of a run
+type Result struct {
+	Fills  []Fill
+	Equity []EquityPoint
+}
+
+// RunTicks runs until the stream closes and returns its error
+func (e *Engine) RunTicks(stream *ibgo.TickStream) (*Result, error) {
+	for t := range stream.Ticks {
+		e.Broker.onTick(t)
+		e.Strategy.OnTick(t, e.Broker)
+	}
+	return e.result(), stream.Err
+}
+
+// RunBars runs until the stream closes and returns its error
+func (e *Engine) RunBars(stream *ibgo.BarStream) (*Result, error) {
+	for bar := range stream.Bars {
+		e.Broker.onBar(bar)
+		e.Strategy.OnBar(bar, e.Broker)
+	}
+	return e.result(), stream.Err
+}
+
+func (e *Engine) result() *Result {
+	return &Result{e.Broker.Fills, e.Broker.Curve}
+}
+
+// MaxDrawdown is the largest fall of equity from a previous high, as a fraction of that high
+func (r *Result) MaxDrawdown() (dd float64) {
+	peak := 0.0
+	for _, p := range r.Equity {
+		if p.Equity > peak {
+			peak = p.Equity
+		} else if peak > 0 && (peak-p.Equity)/peak > dd {
+			dd = (peak - p.Equity) / peak
+		}
+	}
+	return
+}
+
+// Realized sums the profit closed by all fills, commissions deducted
+func (r *Result) Realized() (pnl float64) {
+	for _, f := range r.Fills {
+		pnl += f.Realized
+	}
+	return
+}
+
+// Replay plays stored or downloaded ticks through a TickStream, closing it after the last one or on Cancel
+func Replay(ticks []ibgo.Tick) *ibgo.TickStream {
+	stream := &ibgo.TickStream{Ticks: make(chan ibgo.Tick), Params: func() *ibgo.TickReqParams { return nil }}
+	done := make(chan struct{})
+	var once sync.Once
+	stream.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return nil
+	}
+	go func() {
+		defer close(stream.Ticks)
+		for _, t := range ticks {
+			select {
+			case stream.Ticks <- t:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return stream
+}
+
+// ReplayBars plays stored or downloaded bars through a BarStream, closing it after the last one or on Cancel
+func ReplayBars(bars []ibgo.BarData) *ibgo.BarStream {
+	stream := &ibgo.BarStream{Bars: make(chan ibgo.BarData)}
+	done := make(chan struct{})
+	var once sync.Once
+	stream.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return nil
+	}
+	go func() {
+		defer close(stream.Bars)
+		for _, bar := range bars {
+			select {
+			case stream.Bars <- bar:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return stream
+}
@@ -0,0 +1,238 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// Order types
+const (
+	Market = iota
+	Limit
+	Stop
+)
+
+// Order is a simulated order. Action is "BUY" or "SELL" as in ibgo.Leg
+type Order struct {
+	ID         int64
+	Action     string
+	Quantity   float64
+	Type       int
+	LimitPrice float64
+	StopPrice  float64
+}
+
+// Fill is one execution in the trade log. Realized is the profit closed by it, commission deducted
+type Fill struct {
+	Time       time.Time
+	OrderID    int64
+	Action     string
+	Quantity   float64
+	Price      float64
+	Commission float64
+	Realized   float64
+}
+
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// Broker is what a strategy trades through
+type Broker interface {
+	Place(o Order) (int64, error)
+	Cancel(id int64) bool
+	Position() float64
+	Cash() float64
+	Equity() float64
+}
+
+// SimBroker fills orders on a single instrument against the ticks and bars it is shown.
+// Ticks with a bid and ask fill at the touch, trade and midpoint ticks at their price, and bars open to close.
+// Market and triggered stop orders fill Slippage worse than that price, limit orders at their limit or better
+type SimBroker struct {
+	Slippage   float64
+	Commission func(quantity float64, price float64) float64
+	Multiplier float64
+	cash       float64
+	position   float64
+	avgCost    float64
+	mark       float64
+	bid        float64
+	ask        float64
+	nextID     int64
+	open       []Order
+	Fills      []Fill
+	Curve      []EquityPoint
+}
+
+// NewSimBroker starts with cash and no position. A zero multiplier is taken as 1
+func NewSimBroker(cash float64, multiplier float64) *SimBroker {
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	return &SimBroker{cash: cash, Multiplier: multiplier, nextID: 1, open: make([]Order, 0), Fills: make([]Fill, 0), Curve: make([]EquityPoint, 0)}
+}
+
+// PerShare charges rate per unit traded with a minimum per order, like IB's fixed pricing
+func PerShare(rate float64, min float64) func(quantity float64, price float64) float64 {
+	return func(quantity float64, price float64) float64 {
+		return math.Max(quantity*rate, min)
+	}
+}
+
+// Place queues o to be matched from the next tick or bar on
+func (b *SimBroker) Place(o Order) (int64, error) {
+	if o.Action != "BUY" && o.Action != "SELL" {
+		return 0, fmt.Errorf("Unknown action %v", o.Action)
+	}
+	if o.Quantity <= 0 {
+		return 0, fmt.Errorf("Quantity must be positive")
+	}
+	if o.Type != Market && o.Type != Limit && o.Type != Stop {
+		return 0, fmt.Errorf("Unknown order type %v", o.Type)
+	}
+	o.ID = b.nextID
+	b.nextID++
+	b.open = append(b.open, o)
+	return o.ID, nil
+}
+
+func (b *SimBroker) Cancel(id int64) bool {
+	for i, o := range b.open {
+		if o.ID == id {
+			b.open = append(b.open[:i], b.open[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Open returns the orders not yet filled
+func (b *SimBroker) Open() []Order {
+	return append([]Order(nil), b.open...)
+}
+
+func (b *SimBroker) Position() float64 {
+	return b.position
+}
+
+func (b *SimBroker) Cash() float64 {
+	return b.cash
+}
+
+// Equity is cash plus the position marked at the last price seen
+func (b *SimBroker) Equity() float64 {
+	return b.cash + b.position*b.mark*b.Multiplier
+}
+
+// onTick matches the open orders against t and marks the position to it
+func (b *SimBroker) onTick(t ibgo.Tick) {
+	if t.Bid != 0 && t.Ask != 0 {
+		b.bid, b.ask = t.Bid, t.Ask
+		b.mark = (t.Bid + t.Ask) / 2
+	} else if t.Last != 0 {
+		b.bid, b.ask, b.mark = 0, 0, t.Last
+	} else if t.Midpoint != 0 {
+		b.bid, b.ask, b.mark = 0, 0, t.Midpoint
+	} else {
+		return
+	}
+	buy, sell := b.mark, b.mark
+	if b.bid != 0 {
+		buy, sell = b.ask, b.bid
+	}
+	b.match(t.Time, func(o *Order) (float64, bool) {
+		p := buy
+		if o.Action == "SELL" {
+			p = sell
+		}
+		return b.price(o, p, p, p, p)
+	})
+	b.Curve = append(b.Curve, EquityPoint{t.Time, b.Equity()})
+}
+
+// onBar matches the open orders against bar, marks the position at its close and stamps the curve at its start
+func (b *SimBroker) onBar(bar ibgo.BarData) {
+	b.bid, b.ask = 0, 0
+	b.match(bar.Time, func(o *Order) (float64, bool) {
+		return b.price(o, bar.Open, bar.High, bar.Low, bar.Close)
+	})
+	b.mark = bar.Close
+	b.Curve = append(b.Curve, EquityPoint{bar.Time, b.Equity()})
+}
+
+// price returns where o fills over a move from open through high and low, assuming the extremes are reached
+// after the open
+func (b *SimBroker) price(o *Order, open float64, high float64, low float64, close float64) (float64, bool) {
+	buy := o.Action == "BUY"
+	switch o.Type {
+	case Market:
+		if buy {
+			return open + b.Slippage, true
+		}
+		return open - b.Slippage, true
+	case Limit:
+		if buy && low <= o.LimitPrice {
+			return math.Min(open, o.LimitPrice), true
+		}
+		if !buy && high >= o.LimitPrice {
+			return math.Max(open, o.LimitPrice), true
+		}
+	case Stop:
+		if buy && high >= o.StopPrice {
+			return math.Max(open, o.StopPrice) + b.Slippage, true
+		}
+		if !buy && low <= o.StopPrice {
+			return math.Min(open, o.StopPrice) - b.Slippage, true
+		}
+	}
+	return 0, false
+}
+
+func (b *SimBroker) match(t time.Time, fillPrice func(o *Order) (float64, bool)) {
+	open := b.open[:0]
+	for _, o := range b.open {
+		if p, ok := fillPrice(&o); ok {
+			b.fill(t, o, p)
+		} else {
+			open = append(open, o)
+		}
+	}
+	b.open = open
+}
+
+// fill books an execution, keeping the average cost of the position to work out realised profit
+func (b *SimBroker) fill(t time.Time, o Order, price float64) {
+	qty := o.Quantity
+	if o.Action == "SELL" {
+		qty = -qty
+	}
+	commission := 0.0
+	if b.Commission != nil {
+		commission = b.Commission(o.Quantity, price)
+	}
+	realized := -commission
+	if b.position != 0 && (b.position > 0) != (qty > 0) {
+		closed := math.Min(math.Abs(qty), math.Abs(b.position))
+		if b.position > 0 {
+			realized += closed * (price - b.avgCost) * b.Multiplier
+		} else {
+			realized += closed * (b.avgCost - price) * b.Multiplier
+		}
+	}
+	switch after := b.position + qty; {
+	case after == 0:
+		b.avgCost = 0
+	case b.position == 0 || (b.position > 0) != (after > 0):
+		b.avgCost = price
+	case (b.position > 0) == (qty > 0):
+		b.avgCost = (b.avgCost*b.position + price*qty) / after
+	}
+	b.position += qty
+	b.cash -= qty*price*b.Multiplier + commission
+	b.Fills = append(b.Fills, Fill{t, o.ID, o.Action, o.Quantity, price, commission, realized})
+}
@@ -0,0 +1,177 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+const tolerance = 1e-9
+
+func TestBarFills(t *testing.T) {
+	bar := ibgo.BarData{Open: 10, High: 12, Low: 9, Close: 11}
+	tests := []struct {
+		order  Order
+		filled bool
+		price  float64
+	}{
+		{Order{Action: "BUY", Quantity: 1, Type: Market}, true, 10.1},
+		{Order{Action: "SELL", Quantity: 1, Type: Market}, true, 9.9},
+		{Order{Action: "BUY", Quantity: 1, Type: Limit, LimitPrice: 9.5}, true, 9.5},
+		{Order{Action: "BUY", Quantity: 1, Type: Limit, LimitPrice: 8}, false, 0},
+		{Order{Action: "BUY", Quantity: 1, Type: Limit, LimitPrice: 11}, true, 10},
+		{Order{Action: "SELL", Quantity: 1, Type: Limit, LimitPrice: 11.5}, true, 11.5},
+		{Order{Action: "SELL", Quantity: 1, Type: Limit, LimitPrice: 13}, false, 0},
+		{Order{Action: "SELL", Quantity: 1, Type: Limit, LimitPrice: 9}, true, 10},
+		{Order{Action: "BUY", Quantity: 1, Type: Stop, StopPrice: 11}, true, 11.1},
+		{Order{Action: "BUY", Quantity: 1, Type: Stop, StopPrice: 13}, false, 0},
+		{Order{Action: "BUY", Quantity: 1, Type: Stop, StopPrice: 9}, true, 10.1},
+		{Order{Action: "SELL", Quantity: 1, Type: Stop, StopPrice: 9.5}, true, 9.4},
+		{Order{Action: "SELL", Quantity: 1, Type: Stop, StopPrice: 8}, false, 0},
+	}
+	for _, tt := range tests {
+		b := NewSimBroker(1000, 1)
+		b.Slippage = 0.1
+		if _, err := b.Place(tt.order); err != nil {
+			t.Fatal(err)
+		}
+		b.onBar(bar)
+		if filled := len(b.Fills) == 1; filled != tt.filled {
+			t.Errorf("%+v: filled %v, want %v", tt.order, filled, tt.filled)
+			continue
+		}
+		if tt.filled && math.Abs(b.Fills[0].Price-tt.price) > tolerance {
+			t.Errorf("%+v: filled at %v, want %v", tt.order, b.Fills[0].Price, tt.price)
+		}
+		if open := len(b.Open()); (open == 0) != tt.filled {
+			t.Errorf("%+v: %v orders left open", tt.order, open)
+		}
+	}
+}
+
+func TestTickFills(t *testing.T) {
+	quote := ibgo.Tick{Bid: 9.9, Ask: 10.1}
+	trade := ibgo.Tick{Last: 10}
+	tests := []struct {
+		tick   ibgo.Tick
+		order  Order
+		filled bool
+		price  float64
+	}{
+		{quote, Order{Action: "BUY", Quantity: 1, Type: Market}, true, 10.1},
+		{quote, Order{Action: "SELL", Quantity: 1, Type: Market}, true, 9.9},
+		{quote, Order{Action: "BUY", Quantity: 1, Type: Limit, LimitPrice: 10}, false, 0},
+		{quote, Order{Action: "SELL", Quantity: 1, Type: Limit, LimitPrice: 9.9}, true, 9.9},
+		{trade, Order{Action: "BUY", Quantity: 1, Type: Limit, LimitPrice: 10}, true, 10},
+		{trade, Order{Action: "SELL", Quantity: 1, Type: Stop, StopPrice: 10}, true, 10},
+		{ibgo.Tick{}, Order{Action: "BUY", Quantity: 1, Type: Market}, false, 0},
+	}
+	for _, tt := range tests {
+		b := NewSimBroker(1000, 1)
+		b.Place(tt.order)
+		b.onTick(tt.tick)
+		if filled := len(b.Fills) == 1; filled != tt.filled {
+			t.Errorf("%+v on %+v: filled %v, want %v", tt.order, tt.tick, filled, tt.filled)
+		} else if tt.filled && math.Abs(b.Fills[0].Price-tt.price) > tolerance {
+			t.Errorf("%+v on %+v: filled at %v, want %v", tt.order, tt.tick, b.Fills[0].Price, tt.price)
+		}
+	}
+}
+
+func TestPlaceRejects(t *testing.T) {
+	b := NewSimBroker(1000, 1)
+	for _, o := range []Order{
+		{Action: "HOLD", Quantity: 1},
+		{Action: "BUY", Quantity: 0},
+		{Action: "BUY", Quantity: 1, Type: 7},
+	} {
+		if _, err := b.Place(o); err == nil {
+			t.Errorf("Place(%+v) accepted", o)
+		}
+	}
+	id, _ := b.Place(Order{Action: "BUY", Quantity: 1, Type: Limit, LimitPrice: 1})
+	if !b.Cancel(id) || b.Cancel(id) || len(b.Open()) != 0 {
+		t.Error("Cancel did not remove the order exactly once")
+	}
+}
+
+func TestRealized(t *testing.T) {
+	b := NewSimBroker(10000, 2)
+	b.Commission = PerShare(0.01, 1)
+	tests := []struct {
+		action   string
+		quantity float64
+		price    float64
+		realized float64
+		position float64
+		avgCost  float64
+		cash     float64
+	}{
+		{"BUY", 100, 10, -1, 100, 10, 7999},
+		{"BUY", 100, 12, -1, 200, 11, 5598},
+		{"SELL", 50, 13, 199, 150, 11, 6897},
+		// through flat into a short, only the long part is realised
+		{"SELL", 250, 10, -302.5, -100, 10, 11894.5},
+		{"BUY", 100, 8, 399, 0, 0, 10293.5},
+	}
+	for i, tt := range tests {
+		b.fill(time.Time{}, Order{ID: int64(i), Action: tt.action, Quantity: tt.quantity}, tt.price)
+		f := b.Fills[len(b.Fills)-1]
+		if math.Abs(f.Realized-tt.realized) > tolerance || b.position != tt.position ||
+			math.Abs(b.avgCost-tt.avgCost) > tolerance || math.Abs(b.cash-tt.cash) > tolerance {
+			t.Errorf("fill %v: realised %v, position %v at %v, cash %v, want %v, %v at %v, %v",
+				i, f.Realized, b.position, b.avgCost, b.cash, tt.realized, tt.position, tt.avgCost, tt.cash)
+		}
+	}
+	if got := (&Result{Fills: b.Fills}).Realized(); math.Abs(got-293.5) > tolerance {
+		t.Errorf("Realized = %v, want 293.5", got)
+	}
+}
+
+// buyThenSell buys one on the first bar and sells it on the third
+type buyThenSell struct {
+	bars int
+}
+
+func (s *buyThenSell) OnTick(t ibgo.Tick, broker Broker) {}
+
+func (s *buyThenSell) OnBar(bar ibgo.BarData, broker Broker) {
+	switch s.bars++; s.bars {
+	case 1:
+		broker.Place(Order{Action: "BUY", Quantity: 1, Type: Market})
+	case 3:
+		broker.Place(Order{Action: "SELL", Quantity: 1, Type: Market})
+	}
+}
+
+func TestEngineRunBars(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	bars := []ibgo.BarData{
+		{Time: start, Open: 10, High: 10, Low: 10, Close: 10},
+		{Time: start.Add(time.Minute), Open: 11, High: 12, Low: 11, Close: 12},
+		{Time: start.Add(time.Minute * 2), Open: 12, High: 12, Low: 9, Close: 9},
+		{Time: start.Add(time.Minute * 3), Open: 9, High: 9, Low: 9, Close: 9},
+	}
+	e := &Engine{&buyThenSell{}, NewSimBroker(100, 1)}
+	r, err := e.RunBars(ReplayBars(bars))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Fills) != 2 || r.Fills[0].Price != 11 || r.Fills[1].Price != 9 {
+		t.Fatalf("fills %+v, want a buy at 11 and a sell at 9", r.Fills)
+	}
+	want := []float64{100, 101, 98, 98}
+	for i, p := range r.Equity {
+		if math.Abs(p.Equity-want[i]) > tolerance || !p.Time.Equal(bars[i].Time) {
+			t.Errorf("equity %v = %v at %v, want %v at %v", i, p.Equity, p.Time, want[i], bars[i].Time)
+		}
+	}
+	if got := r.Realized(); got != -2 {
+		t.Errorf("Realized = %v, want -2", got)
+	}
+	if got, want := r.MaxDrawdown(), 3.0/101; math.Abs(got-want) > tolerance {
+		t.Errorf("MaxDrawdown = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package ibgo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContractCache keeps the contract details returned for each normalised contract spec or plain ConID lookup.
+// A lookup keeps all of its rows, as IB returns the same ConID once per exchange for some contracts.
+// With a path it is written to a JSON file CacheSaveDelay after changes and reloaded on start.
+// IB only publishes trading and liquid hours about a week ahead, so entries older than SessionTTL are fetched again
+// even while the rest of the contract is within TTL
+type ContractCache struct {
+	TTL        time.Duration
+	SessionTTL time.Duration
+	path       string
+	mu         sync.Mutex
+	entries    map[string]cachedEntry
+	saving     *time.Timer
+	saveErr    error
+}
+
+// DefaultSessionTTL is the SessionTTL of new caches
+var DefaultSessionTTL = time.Hour * 12
+
+// CacheSaveDelay is how long after a Put the cache file is written, so that a burst of lookups writes it once
+var CacheSaveDelay = time.Second * 2
+
+type cachedEntry struct {
+	Data    []ContractData
+	Fetched time.Time
+}
+
+type contractCacheFile struct {
+	Entries map[string]cachedEntry
+}
+
+// NewContractCache creates a cache whose entries live for ttl, zero meaning forever. An empty path keeps it in memory
+func NewContractCache(ttl time.Duration, path string) (*ContractCache, error) {
+	cache := &ContractCache{TTL: ttl, SessionTTL: DefaultSessionTTL, path: path, entries: make(map[string]cachedEntry)}
+	if path == "" {
+		return cache, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	} else if err != nil {
+		return nil, err
+	}
+	f := contractCacheFile{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	if f.Entries != nil {
+		cache.entries = f.Entries
+	}
+	return cache, nil
+}
+
+func (cache *ContractCache) fresh(t time.Time) bool {
+	return cache.TTL == 0 || time.Since(t) < cache.TTL
+}
+
+// usable reports whether an entry can be answered from, its sessions included
+func (cache *ContractCache) usable(e cachedEntry) bool {
+	if !cache.fresh(e.Fetched) || len(e.Data) == 0 {
+		return false
+	}
+	if cache.SessionTTL == 0 || time.Since(e.Fetched) < cache.SessionTTL {
+		return true
+	}
+	for _, d := range e.Data {
+		if len(d.TradingHours) > 0 || len(d.LiquidHours) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the cached details matching con
+func (cache *ContractCache) Get(con Contract) ([]ContractData, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	e, ok := cache.entries[cacheKey(con)]
+	if !ok || !cache.usable(e) {
+		return nil, false
+	}
+	return append([]ContractData(nil), e.Data...), true
+}
+
+// Put records the details returned for con. The file is written later, a failure to do so is reported
+// by Save and Err
+func (cache *ContractCache) Put(con Contract, data []ContractData) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.entries[cacheKey(con)] = cachedEntry{append([]ContractData(nil), data...), time.Now()}
+	if cache.path != "" && cache.saving == nil {
+		cache.saving = time.AfterFunc(CacheSaveDelay, func() {
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			cache.saving = nil
+			cache.saveErr = cache.save()
+		})
+	}
+}
+
+// Err returns the error of the last delayed write of the file, nil once a later write succeeds
+func (cache *ContractCache) Err() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.saveErr
+}
+
+// Save writes pending changes to the file at once
+func (cache *ContractCache) Save() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.saving != nil {
+		cache.saving.Stop()
+		cache.saving = nil
+	}
+	cache.saveErr = cache.save()
+	return cache.saveErr
+}
+
+// Purge drops expired entries and writes the file
+func (cache *ContractCache) Purge() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for key, e := range cache.entries {
+		if !cache.fresh(e.Fetched) {
+			delete(cache.entries, key)
+		}
+	}
+	cache.saveErr = cache.save()
+	return cache.saveErr
+}
+
+func (cache *ContractCache) save() error {
+	if cache.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(contractCacheFile{cache.entries})
+	if err != nil {
+		return err
+	}
+	tmp := cache.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cache.path)
+}
+
+// cacheKey normalises con. A plain ConID lookup is keyed by the ConID alone
+func cacheKey(con Contract) string {
+	right := strings.ToUpper(con.Right)
+	switch right {
+	case "CALL":
+		right = "C"
+	case "PUT":
+		right = "P"
+	}
+	fields := []string{
+		strings.ToUpper(con.Symbol),
+		strings.ToUpper(con.SecType),
+		con.LastTradeDateOrContractMonth,
+		right,
+		con.Multiplier,
+		strings.ToUpper(con.Exchange),
+		strings.ToUpper(con.PrimaryExchange),
+		strings.ToUpper(con.Currency),
+		strings.ToUpper(con.LocalSymbol),
+		strings.ToUpper(con.TradingClass),
+		strings.ToUpper(con.SecIDType),
+		con.SecID,
+	}
+	if con.Strike != 0 {
+		fields = append(fields, strconv.FormatFloat(con.Strike, 'f', -1, 64))
+	}
+	if con.IncludeExpired {
+		fields = append(fields, "EXPIRED")
+	}
+	if strings.Join(fields, "") == "" {
+		return "#" + strconv.FormatInt(con.ConID, 10)
+	}
+	if con.ConID != 0 {
+		fields = append(fields, strconv.FormatInt(con.ConID, 10))
+	}
+	return strings.Join(fields, "|")
+}
+
+// UseContractCache makes ReqContractDetails and everything built on it answer from cache when possible
+func (c *IBClient) UseContractCache(cache *ContractCache) {
+	c.contractCache = cache
+}
+
+func (c *IBClient) cachePut(con Contract, data []ContractData) {
+	if c.contractCache != nil && len(data) > 0 {
+		c.contractCache.Put(con, data)
+	}
+}
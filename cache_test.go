@@ -0,0 +1,98 @@
+package ibgo
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func contractRow(conID int64, exchange string) ContractData {
+	d := ContractData{}
+	d.ConID, d.Symbol, d.SecType, d.Exchange = conID, "ES", "CONTFUT", exchange
+	return d
+}
+
+func TestCacheKeepsRowsSharingConID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contracts.json")
+	cache, err := NewContractCache(0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := Contract{Symbol: "ES", SecType: "CONTFUT"}
+	rows := []ContractData{contractRow(1, "GLOBEX"), contractRow(1, "QBALGO")}
+	cache.Put(spec, rows)
+	cache.Put(Contract{ConID: 1}, rows[1:])
+	tests := []struct {
+		con  Contract
+		want []ContractData
+	}{
+		{spec, rows},
+		{Contract{ConID: 1}, rows[1:]},
+		{Contract{Symbol: "es", SecType: "contfut"}, rows},
+		{Contract{Symbol: "ES", SecType: "CONTFUT", Exchange: "GLOBEX"}, nil},
+		{Contract{ConID: 2}, nil},
+	}
+	check := func(cache *ContractCache) {
+		for _, tt := range tests {
+			got, ok := cache.Get(tt.con)
+			if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%+v) = %v, %v, want %v", tt.con, got, ok, tt.want)
+			}
+		}
+	}
+	check(cache)
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewContractCache(0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(reloaded)
+}
+
+func TestCacheExpiry(t *testing.T) {
+	cache, _ := NewContractCache(time.Hour, "")
+	plain, withHours := contractRow(1, "SMART"), contractRow(2, "SMART")
+	withHours.TradingHours = []Session{{time.Now(), time.Now().Add(time.Hour)}}
+	tests := []struct {
+		data    ContractData
+		fetched time.Duration
+		ok      bool
+	}{
+		{plain, 0, true},
+		{plain, time.Hour * 2, false},
+		{withHours, time.Hour / 2, true},
+		{withHours, 0, true},
+	}
+	for _, tt := range tests {
+		con := Contract{ConID: tt.data.ConID}
+		cache.entries[cacheKey(con)] = cachedEntry{[]ContractData{tt.data}, time.Now().Add(-tt.fetched)}
+		if _, ok := cache.Get(con); ok != tt.ok {
+			t.Errorf("%v fetched %v ago: hit %v, want %v", tt.data.ConID, tt.fetched, ok, tt.ok)
+		}
+	}
+	cache.SessionTTL = time.Minute
+	cache.entries[cacheKey(Contract{ConID: 2})] = cachedEntry{[]ContractData{withHours}, time.Now().Add(-time.Hour / 2)}
+	if _, ok := cache.Get(Contract{ConID: 2}); ok {
+		t.Error("sessions older than SessionTTL were answered from cache")
+	}
+}
+
+func TestCacheSaveErrors(t *testing.T) {
+	cache, err := NewContractCache(0, filepath.Join(t.TempDir(), "missing", "contracts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put(Contract{ConID: 1}, []ContractData{contractRow(1, "SMART")})
+	if err := cache.Save(); err == nil {
+		t.Error("Save to a missing directory succeeded")
+	}
+	if cache.Err() == nil {
+		t.Error("Err does not report the failed write")
+	}
+	if _, ok := cache.Get(Contract{ConID: 1}); !ok {
+		t.Error("a failed write dropped the entry")
+	}
+}
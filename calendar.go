@@ -0,0 +1,293 @@
+package ibgo
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+var ErrNoSession = errors.New("No session known at the given time")
+
+// shape is a session by its wall clock times relative to the midnight of the day it ends on
+type shape struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// calendar returns the sessions of any day. IB only publishes sessions about a week around the present: days
+// within that span get the published sessions, other days follow the weekly pattern of the published ones.
+// A weekday closed within the span takes the pattern of another weekday. Holidays and early closes outside of
+// the span are not known
+type calendar struct {
+	loc      *time.Location
+	sessions []Session
+	first    time.Time
+	last     time.Time
+	week     [7][]shape
+	lead     int
+}
+
+func midnight(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// endDay returns the midnight of the day s ends on, a session ending at midnight belonging to the day before
+func (c *calendar) endDay(s Session) time.Time {
+	return midnight(s.End.Add(-time.Nanosecond), c.loc)
+}
+
+// wallOffset returns the wall clock time of t counted from day, which may be negative or beyond a day
+func wallOffset(t time.Time, day time.Time) time.Duration {
+	t = t.In(day.Location())
+	days := int(math.Round(midnight(t, day.Location()).Sub(day).Hours() / 24))
+	h, m, s := t.Clock()
+	return time.Duration(days)*24*time.Hour + time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+}
+
+// atOffset is the inverse of wallOffset
+func atOffset(day time.Time, off time.Duration) time.Time {
+	days := int(off / (24 * time.Hour))
+	if off < 0 && off%(24*time.Hour) != 0 {
+		days--
+	}
+	off -= time.Duration(days) * 24 * time.Hour
+	return time.Date(day.Year(), day.Month(), day.Day()+days, int(off/time.Hour), int(off%time.Hour/time.Minute), int(off%time.Minute/time.Second), 0, day.Location())
+}
+
+// newCalendar returns nil without sessions
+func newCalendar(sessions []Session, loc *time.Location) *calendar {
+	if len(sessions) == 0 {
+		return nil
+	}
+	c := &calendar{loc: loc, sessions: sessions}
+	c.first, c.last = c.endDay(sessions[0]), c.endDay(sessions[len(sessions)-1])
+	seen := [7]bool{}
+	for i := 0; i < len(sessions); {
+		day := c.endDay(sessions[i])
+		shapes := make([]shape, 0, 1)
+		for ; i < len(sessions) && c.endDay(sessions[i]).Equal(day); i++ {
+			sh := shape{wallOffset(sessions[i].Start, day), wallOffset(sessions[i].End, day)}
+			if lead := int((-sh.start + 24*time.Hour - 1) / (24 * time.Hour)); lead > c.lead {
+				c.lead = lead
+			}
+			shapes = append(shapes, sh)
+		}
+		if wd := day.Weekday(); !seen[wd] {
+			seen[wd] = true
+			c.week[wd] = shapes
+		}
+	}
+	// weekdays without sessions in the span were holidays, borrow the nearest weekday that traded
+	for wd := time.Monday; wd <= time.Friday; wd++ {
+		if seen[wd] {
+			continue
+		}
+		for d := 1; d < 5; d++ {
+			if other := time.Monday + (wd-time.Monday+time.Weekday(d))%5; seen[other] {
+				c.week[wd] = c.week[other]
+				break
+			}
+		}
+	}
+	return c
+}
+
+// day returns the ordered sessions ending on the day starting at date, a midnight
+func (c *calendar) day(date time.Time) []Session {
+	if !date.Before(c.first) && !date.After(c.last) {
+		i := sort.Search(len(c.sessions), func(i int) bool { return !c.endDay(c.sessions[i]).Before(date) })
+		j := i
+		for j < len(c.sessions) && c.endDay(c.sessions[j]).Equal(date) {
+			j++
+		}
+		return c.sessions[i:j]
+	}
+	shapes := c.week[date.Weekday()]
+	sessions := make([]Session, len(shapes))
+	for i, sh := range shapes {
+		sessions[i] = Session{atOffset(date, sh.start), atOffset(date, sh.end)}
+	}
+	return sessions
+}
+
+// find returns the session containing t
+func (c *calendar) find(t time.Time) (Session, bool) {
+	day := midnight(t, c.loc)
+	for d := 0; d <= c.lead; d++ {
+		for _, s := range c.day(day.AddDate(0, 0, d)) {
+			if !t.Before(s.Start) && t.Before(s.End) {
+				return s, true
+			}
+		}
+	}
+	return Session{}, false
+}
+
+// next returns the first session ending after t, looking at most two weeks ahead
+func (c *calendar) next(t time.Time) (Session, bool) {
+	day := midnight(t, c.loc)
+	for d := 0; d < 14; d++ {
+		for _, s := range c.day(day.AddDate(0, 0, d)) {
+			if s.End.After(t) {
+				return s, true
+			}
+		}
+	}
+	return Session{}, false
+}
+
+// between returns the sessions overlapping [start, end)
+func (c *calendar) between(start time.Time, end time.Time) []Session {
+	sessions := make([]Session, 0)
+	last := midnight(end, c.loc).AddDate(0, 0, c.lead)
+	for day := midnight(start, c.loc); !day.After(last); day = day.AddDate(0, 0, 1) {
+		for _, s := range c.day(day) {
+			if s.End.After(start) && s.Start.Before(end) {
+				sessions = append(sessions, s)
+			}
+		}
+	}
+	return sessions
+}
+
+// calendar returns the trading or, with useRTH, the liquid hours calendar. It is nil when IB published no sessions
+func (ins *Instrument) calendar(useRTH bool) *calendar {
+	ins.calendarOnce.Do(func() {
+		loc, err := time.LoadLocation(ins.Detail.TimeZoneID)
+		if err != nil {
+			loc = time.UTC
+		}
+		ins.tradingCal = newCalendar(ins.Detail.TradingHours, loc)
+		ins.liquidCal = newCalendar(ins.Detail.LiquidHours, loc)
+	})
+	if useRTH {
+		return ins.liquidCal
+	}
+	return ins.tradingCal
+}
+
+// Sessions returns the trading or, with useRTH, the liquid hours sessions overlapping [start, end).
+// Outside the span IB published sessions for they follow its weekly pattern. ErrNoSession means none are known
+func (ins *Instrument) Sessions(start time.Time, end time.Time, useRTH bool) ([]Session, error) {
+	c := ins.calendar(useRTH)
+	if c == nil {
+		return nil, ErrNoSession
+	}
+	return c.between(start, end), nil
+}
+
+// SessionFor returns the trading session containing t. in is false when t falls between sessions,
+// ErrNoSession means it cannot be known
+func (ins *Instrument) SessionFor(t time.Time) (s Session, in bool, err error) {
+	c := ins.calendar(false)
+	if c == nil {
+		return Session{}, false, ErrNoSession
+	}
+	s, in = c.find(t)
+	return
+}
+
+// IsOpen reports whether t falls within trading hours
+func (ins *Instrument) IsOpen(t time.Time) (bool, error) {
+	_, in, err := ins.SessionFor(t)
+	return in, err
+}
+
+// IsRTH reports whether t falls within liquid (regular) trading hours
+func (ins *Instrument) IsRTH(t time.Time) (bool, error) {
+	c := ins.calendar(true)
+	if c == nil {
+		return false, ErrNoSession
+	}
+	_, in := c.find(t)
+	return in, nil
+}
+
+// NextOpen returns the start of the first trading session after t
+func (ins *Instrument) NextOpen(t time.Time) (time.Time, error) {
+	c := ins.calendar(false)
+	if c == nil {
+		return time.Time{}, ErrNoSession
+	}
+	s, ok := c.next(t)
+	if ok && !t.Before(s.Start) {
+		s, ok = c.next(s.End)
+	}
+	if !ok {
+		return time.Time{}, ErrNoSession
+	}
+	return s.Start, nil
+}
+
+// NextClose returns the end of the trading session containing t, or of the next one when closed
+func (ins *Instrument) NextClose(t time.Time) (time.Time, error) {
+	c := ins.calendar(false)
+	if c == nil {
+		return time.Time{}, ErrNoSession
+	}
+	s, ok := c.next(t)
+	if !ok {
+		return time.Time{}, ErrNoSession
+	}
+	return s.End, nil
+}
+
+// TagTicks sets the RTH, SessionStart and SessionEnd bits of ordered ticks.
+// SessionStart marks the first tick of each trading session, which the first of ticks only is when it falls
+// within the first second of the session, and SessionEnd the last one when a later tick shows the session is over.
+// Without known sessions the ticks are left as they are
+func (ins *Instrument) TagTicks(ticks []Tick) {
+	tagger := ins.newSessionTagger()
+	var last *Tick
+	var lastSession Session
+	for i := range ticks {
+		t := &ticks[i]
+		tagger.tag(t)
+		s, in, _ := ins.SessionFor(t.Time)
+		if last != nil && (!in || s != lastSession) {
+			last.SetSessionEnd()
+			last = nil
+		}
+		if in {
+			last, lastSession = t, s
+		}
+	}
+}
+
+type sessionTagger struct {
+	ins     *Instrument
+	current Session
+	seen    bool
+}
+
+func (ins *Instrument) newSessionTagger() *sessionTagger {
+	return &sessionTagger{ins: ins}
+}
+
+// tag marks a live tick. SessionStart is set on the first tick of a session after a tick the tagger saw outside
+// it, or without an earlier tick when it lies within the first second of the session, so that a stream or page
+// starting mid-session marks no start. The end of a session cannot be known ahead of time on a stream,
+// so SessionEnd is set on ticks within the last second of the session
+func (st *sessionTagger) tag(t *Tick) {
+	if rth, _ := st.ins.IsRTH(t.Time); rth {
+		t.SetRTH()
+	}
+	s, in, _ := st.ins.SessionFor(t.Time)
+	seen := st.seen
+	st.seen = true
+	if !in {
+		st.current = Session{}
+		return
+	}
+	if s != st.current {
+		st.current = s
+		if seen || t.Time.Before(s.Start.Add(time.Second)) {
+			t.SetSessionStart()
+		}
+	}
+	if !t.Time.Before(s.End.Add(-time.Second)) {
+		t.SetSessionEnd()
+	}
+}
@@ -0,0 +1,58 @@
+package ibgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTagTicks(t *testing.T) {
+	ins, loc := weekInstrument(t)
+	at := func(day, h, m, s int) time.Time { return time.Date(2024, 1, day, h, m, s, 0, loc) }
+	tests := []struct {
+		name  string
+		times []time.Time
+		masks []TickMask
+	}{
+		{"first tick mid-session", []time.Time{at(9, 11, 0, 0), at(9, 11, 0, 1)}, []TickMask{RTH, RTH}},
+		{"first tick at the open", []time.Time{at(9, 9, 30, 0), at(9, 11, 0, 0)}, []TickMask{RTH | SessionStart, RTH}},
+		{"tick before the open", []time.Time{at(9, 9, 0, 0), at(9, 11, 0, 0)}, []TickMask{0, RTH | SessionStart}},
+		{"across days", []time.Time{at(9, 15, 0, 0), at(10, 10, 0, 0)}, []TickMask{RTH | SessionEnd, RTH | SessionStart}},
+		{"at the close", []time.Time{at(9, 15, 59, 59)}, []TickMask{RTH | SessionEnd}},
+		// days outside the published week follow its pattern
+		{"before the published week", []time.Time{at(2, 11, 0, 0), at(3, 9, 45, 0)}, []TickMask{RTH | SessionEnd, RTH | SessionStart}},
+	}
+	for _, tt := range tests {
+		ticks := make([]Tick, len(tt.times))
+		for i := range ticks {
+			ticks[i].Time = tt.times[i]
+		}
+		ins.TagTicks(ticks)
+		for i := range ticks {
+			if ticks[i].Mask != tt.masks[i] {
+				t.Errorf("%v: tick %v tagged %b, want %b", tt.name, i, ticks[i].Mask, tt.masks[i])
+			}
+		}
+	}
+}
+
+func TestSessionTaggerStream(t *testing.T) {
+	ins, loc := weekInstrument(t)
+	tagger := ins.newSessionTagger()
+	tests := []struct {
+		t    time.Time
+		mask TickMask
+	}{
+		{time.Date(2024, 1, 9, 11, 0, 0, 0, loc), RTH},
+		{time.Date(2024, 1, 9, 15, 59, 59, 500, loc), RTH | SessionEnd},
+		{time.Date(2024, 1, 9, 17, 0, 0, 0, loc), 0},
+		{time.Date(2024, 1, 10, 9, 31, 0, 0, loc), RTH | SessionStart},
+		{time.Date(2024, 1, 10, 9, 32, 0, 0, loc), RTH},
+	}
+	for _, tt := range tests {
+		tick := Tick{Time: tt.t}
+		tagger.tag(&tick)
+		if tick.Mask != tt.mask {
+			t.Errorf("tick at %v tagged %b, want %b", tt.t, tick.Mask, tt.mask)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -46,6 +47,12 @@ type IBClient struct {
 	order          chan *quote
 	static         chan *quote
 	historical     chan bool
+	cacheMu        sync.Mutex
+	marketRules    map[int64]*MarketRule
+	smartComps     map[string][]SmartComponent
+	bulletinsOn    bool
+	flights        map[string]*flight
+	contractCache  *ContractCache
 	handshakeInfo
 }
 
@@ -195,9 +202,10 @@ func (c *IBClient) receiver(done chan error, cancel chan struct{}) {
 			done <- err
 			return
 		case cancelID := <-c.unRegisterChan:
-			idChan := c.registry[cancelID]
-			delete(c.registry, cancelID)
-			close(idChan)
+			if idChan, ok := c.registry[cancelID]; ok {
+				delete(c.registry, cancelID)
+				close(idChan)
+			}
 		case msg := <-msgCh:
 			respCh, ok := c.registry[msg.id]
 			if ok {
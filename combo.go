@@ -0,0 +1,81 @@
+package ibgo
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Leg struct {
+	Instrument *Instrument
+	Ratio      int64
+	Action     string
+}
+
+// NewCombo builds a BAG instrument out of resolved legs. Trading hours and time zone are taken from the first leg
+func (c *IBClient) NewCombo(legs ...Leg) (*Instrument, error) {
+	if len(legs) < 2 {
+		return nil, fmt.Errorf("A combo needs at least two legs")
+	}
+	first := legs[0].Instrument.contract
+	symbols := make([]string, 0, len(legs))
+	seen := make(map[string]struct{})
+	exchange := first.Exchange
+	con := Contract{SecType: "BAG", Currency: first.Currency}
+	con.ComboLegs = make([]ComboLeg, len(legs))
+	for i, leg := range legs {
+		lc := leg.Instrument.contract
+		if lc.Currency != con.Currency {
+			return nil, fmt.Errorf("Combo legs must share one currency")
+		}
+		if leg.Ratio <= 0 {
+			return nil, fmt.Errorf("Combo leg ratio must be positive")
+		}
+		if leg.Action != "BUY" && leg.Action != "SELL" {
+			return nil, fmt.Errorf("Combo leg action must be BUY or SELL")
+		}
+		if _, ok := seen[lc.Symbol]; !ok {
+			seen[lc.Symbol] = struct{}{}
+			symbols = append(symbols, lc.Symbol)
+		}
+		if lc.Exchange != exchange {
+			exchange = "SMART"
+		}
+		con.ComboLegs[i] = ComboLeg{ContractID: lc.ConID, Ratio: leg.Ratio, Action: leg.Action, Exchange: lc.Exchange, ExemptCode: -1}
+	}
+	con.Symbol = strings.Join(symbols, ",")
+	con.Exchange = exchange
+	detail := legs[0].Instrument.Detail
+	detail.MarketRuleIDs = ""
+	return newInstrument(ContractData{con, detail}, c), nil
+}
+
+// CalendarSpread sells near and buys far
+func (c *IBClient) CalendarSpread(near *Instrument, far *Instrument) (*Instrument, error) {
+	if near.contract.Symbol != far.contract.Symbol || near.contract.SecType != far.contract.SecType {
+		return nil, fmt.Errorf("Calendar spread legs must share symbol and security type")
+	}
+	if near.contract.LastTradeDateOrContractMonth >= far.contract.LastTradeDateOrContractMonth {
+		return nil, fmt.Errorf("Near leg must expire before far leg")
+	}
+	return c.NewCombo(Leg{near, 1, "SELL"}, Leg{far, 1, "BUY"})
+}
+
+// VerticalSpread buys long and sells short, both options of the same expiry and right
+func (c *IBClient) VerticalSpread(long *Instrument, short *Instrument) (*Instrument, error) {
+	lc, sc := long.contract, short.contract
+	if lc.SecType != "OPT" && lc.SecType != "FOP" || lc.SecType != sc.SecType {
+		return nil, fmt.Errorf("Vertical spread legs must be options")
+	}
+	if lc.Symbol != sc.Symbol || lc.LastTradeDateOrContractMonth != sc.LastTradeDateOrContractMonth || lc.Right != sc.Right {
+		return nil, fmt.Errorf("Vertical spread legs must share symbol, expiry and right")
+	}
+	if lc.Strike == sc.Strike {
+		return nil, fmt.Errorf("Vertical spread legs must have different strikes")
+	}
+	return c.NewCombo(Leg{long, 1, "BUY"}, Leg{short, 1, "SELL"})
+}
+
+// RatioSpread buys longRatio of long against shortRatio of short
+func (c *IBClient) RatioSpread(long *Instrument, longRatio int64, short *Instrument, shortRatio int64) (*Instrument, error) {
+	return c.NewCombo(Leg{long, longRatio, "BUY"}, Leg{short, shortRatio, "SELL"})
+}
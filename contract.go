@@ -2,6 +2,9 @@ package ibgo
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -99,7 +102,7 @@ type BondContractDetail struct {
 	CouponType        string
 	Callable          bool
 	Putable           bool
-	Coupon            int64
+	Coupon            float64
 	Convertible       bool
 	Maturity          string
 	IssueDate         string
@@ -111,8 +114,96 @@ type BondContractDetail struct {
 
 type ContractDataEnd struct{}
 
+type PriceIncrement struct {
+	LowEdge   float64
+	Increment float64
+}
+
+type MarketRule struct {
+	ID         int64
+	Increments []PriceIncrement
+}
+
+// Increment returns the price increment that applies at price
+func (r *MarketRule) Increment(price float64) float64 {
+	price = math.Abs(price)
+	inc := 0.0
+	for _, pi := range r.Increments {
+		if price < pi.LowEdge {
+			break
+		}
+		inc = pi.Increment
+	}
+	if inc == 0 && len(r.Increments) > 0 {
+		inc = r.Increments[0].Increment
+	}
+	return inc
+}
+
+// Rounding direction
+const (
+	RoundNearest = iota
+	RoundUp
+	RoundDown
+)
+
+// Round moves price onto the increment ladder in the given direction
+func (r *MarketRule) Round(price float64, direction int) float64 {
+	return roundToIncrement(price, r.Increment(price), direction)
+}
+
+func roundToIncrement(price float64, inc float64, direction int) float64 {
+	if inc <= 0 {
+		return price
+	}
+	const eps = 1e-9
+	n := price / inc
+	switch direction {
+	case RoundUp:
+		n = math.Ceil(n - eps)
+	case RoundDown:
+		n = math.Floor(n + eps)
+	default:
+		n = math.Round(n)
+	}
+	scale := math.Pow10(incrementDecimals(inc))
+	return math.Round(n*inc*scale) / scale
+}
+
+func incrementDecimals(inc float64) int {
+	str := strconv.FormatFloat(inc, 'f', -1, 64)
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		return len(str) - i - 1
+	}
+	return 0
+}
+
+type SmartComponent struct {
+	BitNumber      int64
+	Exchange       string
+	ExchangeLetter string
+}
+
+// SmartComponents maps single letter exchange codes to the exchanges behind them
+type SmartComponents map[string]SmartComponent
+
+// MarketRuleID returns the market rule ID in effect on exchange
+func (d *ContractDetail) MarketRuleID(exchange string) (id int64, err error) {
+	exchanges := strings.Split(d.ValidExchange, ",")
+	rules := strings.Split(d.MarketRuleIDs, ",")
+	if len(exchanges) != len(rules) {
+		return 0, fmt.Errorf("Market rules don't match valid exchanges")
+	}
+	for i, ex := range exchanges {
+		if ex == exchange {
+			return strconv.ParseInt(rules[i], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("No market rule for exchange %v", exchange)
+}
+
 func (c Contract) String() string {
-	return fmt.Sprintf("ID: %d\tSymbol: %s\tSecType: %v\tCurrency: %v\tExchange: %v\tPrimaryExchaneg: %v\tLocalSymbol: %v\tExpire: %v\tMultiplier: %v", c.ConID, c.Symbol, c.SecType, c.Currency, c.Exchange, c.PrimaryExchange, c.LocalSymbol, c.LastTradeDateOrContractMonth, c.Multiplier)
+	return FormatContract(c)
 }
 
 func (c ContractData) String() string {
@@ -1,13 +1,255 @@
 package db
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/jinspiration/ibgo"
+)
+
+// Measurements points are written to
+const (
+	BarsMeasurement         = "bars"
+	TicksMeasurement        = "ticks"
+	TypedTicksMeasurement   = "typedticks"
+	RealtimeBarsMeasurement = "realtimebars"
 )
 
+// BatchSize is the number of points sent per write request
+var BatchSize = 5000
+
+// QueueSize is the number of points buffered ahead of the database. Writes block once it is full
+var QueueSize = 50000
+
+// FlushInterval bounds how long a point waits in a partial batch
+var FlushInterval = time.Second
+
+var ErrClosed = fmt.Errorf("Database is closed")
+
+// DB batches line protocol points into an InfluxDB bucket in the background
 type DB struct {
-	name string
+	bucket  string
+	client  influxdb2.Client
+	write   api.WriteAPIBlocking
+	query   api.QueryAPI
+	lines   chan string
+	flush   chan chan error
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	closeMu sync.RWMutex
+	closed  bool
+	errMu   sync.Mutex
+	err     error
+}
+
+func Open(url string, token string, org string, bucket string) *DB {
+	client := influxdb2.NewClient(url, token)
+	db := &DB{
+		bucket:  bucket,
+		client:  client,
+		write:   client.WriteAPIBlocking(org, bucket),
+		query:   client.QueryAPI(org),
+		lines:   make(chan string, QueueSize),
+		flush:   make(chan chan error),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go db.run()
+	return db
+}
+
+func (db *DB) run() {
+	batch := make([]string, 0, BatchSize)
+	send := func() (err error) {
+		if len(batch) == 0 {
+			return
+		}
+		err = db.write.WriteRecord(context.Background(), batch...)
+		if err != nil {
+			db.setErr(err)
+		}
+		batch = batch[:0]
+		return
+	}
+	drain := func() (err error) {
+		for {
+			select {
+			case l := <-db.lines:
+				batch = append(batch, l)
+				if len(batch) >= BatchSize {
+					if e := send(); e != nil {
+						err = e
+					}
+				}
+			default:
+				if e := send(); e != nil {
+					err = e
+				}
+				return
+			}
+		}
+	}
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case l := <-db.lines:
+			batch = append(batch, l)
+			if len(batch) >= BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ch := <-db.flush:
+			ch <- drain()
+		case <-db.closing:
+			drain()
+			close(db.done)
+			return
+		}
+	}
+}
+
+func (db *DB) setErr(err error) {
+	db.errMu.Lock()
+	if db.err == nil {
+		db.err = err
+	}
+	db.errMu.Unlock()
+}
+
+// Err returns the first error met writing in the background
+func (db *DB) Err() error {
+	db.errMu.Lock()
+	defer db.errMu.Unlock()
+	return db.err
+}
+
+// Write queues points, blocking while the queue is full
+func (db *DB) Write(lines ...string) error {
+	db.closeMu.RLock()
+	defer db.closeMu.RUnlock()
+	if db.closed {
+		return ErrClosed
+	}
+	for _, l := range lines {
+		db.lines <- l
+	}
+	return nil
+}
+
+// TryWrite queues a point unless the queue is full
+func (db *DB) TryWrite(line string) bool {
+	db.closeMu.RLock()
+	defer db.closeMu.RUnlock()
+	if db.closed {
+		return false
+	}
+	select {
+	case db.lines <- line:
+		return true
+	default:
+		return false
+	}
+}
+
+// Flush writes out everything queued so far
+func (db *DB) Flush() error {
+	ch := make(chan error)
+	select {
+	case db.flush <- ch:
+		return <-ch
+	case <-db.closing:
+		return ErrClosed
+	}
+}
+
+// Close flushes the queue and releases the client. Writes still blocked on a full queue finish first
+func (db *DB) Close() error {
+	db.once.Do(func() {
+		db.closeMu.Lock()
+		db.closed = true
+		close(db.closing)
+		db.closeMu.Unlock()
+		<-db.done
+		db.client.Close()
+	})
+	return db.Err()
+}
+
+func (db *DB) WriteBars(ins *ibgo.Instrument, barSize ibgo.BarSize, bars []ibgo.BarData) error {
+	tags := ins.LineTags() + ",barSize=" + ibgo.EscapeTag(string(barSize))
+	for i := range bars {
+		if err := db.Write(bars[i].ToLineProtocol(BarsMeasurement, tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) WriteTicks(ins *ibgo.Instrument, ticks []ibgo.Tick) error {
+	tags := ins.LineTags()
+	for i := range ticks {
+		if err := db.Write(ticks[i].ToLineProtocol(TicksMeasurement, tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) WriteTypedTicks(ins *ibgo.Instrument, ticks []ibgo.TypedTick) error {
+	tags := ins.LineTags()
+	for i := range ticks {
+		if err := db.Write(ticks[i].ToLineProtocol(TypedTicksMeasurement, tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) WriteRealtimeBars(ins *ibgo.Instrument, bars []ibgo.RealtimeBar) error {
+	tags := ins.LineTags()
+	for i := range bars {
+		if err := db.Write(bars[i].ToLineProtocol(RealtimeBarsMeasurement, tags)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (db *DB) connect() {
-	influxdb2.NewClient("http://localhost:8086", "my-token")
+// QueryBars reads back the bars of ins written with barSize in [start, end), ordered by time
+func (db *DB) QueryBars(ins *ibgo.Instrument, barSize ibgo.BarSize, start time.Time, end time.Time) (bars []ibgo.BarData, err error) {
+	flux := fmt.Sprintf(`from(bucket: %q)
+  |> range(start: %v, stop: %v)
+  |> filter(fn: (r) => r._measurement == %q and r.conID == "%v" and r.barSize == %q)
+  |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+  |> group()
+  |> sort(columns: ["_time"])`,
+		db.bucket, start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano),
+		BarsMeasurement, ins.ConID(), string(barSize))
+	result, err := db.query.Query(context.Background(), flux)
+	if err != nil {
+		return
+	}
+	defer result.Close()
+	bars = make([]ibgo.BarData, 0)
+	for result.Next() {
+		r := result.Record()
+		bar := ibgo.BarData{Time: r.Time()}
+		bar.Open, _ = r.ValueByKey("open").(float64)
+		bar.High, _ = r.ValueByKey("high").(float64)
+		bar.Low, _ = r.ValueByKey("low").(float64)
+		bar.Close, _ = r.ValueByKey("close").(float64)
+		bar.Volume, _ = r.ValueByKey("volume").(int64)
+		bar.WAP, _ = r.ValueByKey("wap").(float64)
+		bar.TradeCount, _ = r.ValueByKey("count").(int64)
+		bars = append(bars, bar)
+	}
+	err = result.Err()
+	return
 }
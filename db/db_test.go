@@ -0,0 +1,215 @@
+package db
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// influx records the line protocol written to it and answers queries with csv
+type influx struct {
+	mu       sync.Mutex
+	writes   [][]string
+	query    string
+	csv      string
+	failures int
+}
+
+func (f *influx) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/api/v2/write":
+		if f.failures > 0 {
+			f.failures--
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":"invalid","message":"bad line"}`))
+			return
+		}
+		f.writes = append(f.writes, strings.Split(strings.TrimSpace(string(body)), "\n"))
+		w.WriteHeader(http.StatusNoContent)
+	case "/api/v2/query":
+		f.query = string(body)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Write([]byte(f.csv))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *influx) lines() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines := make([]string, 0)
+	for _, batch := range f.writes {
+		lines = append(lines, batch...)
+	}
+	return lines
+}
+
+func (f *influx) requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.writes)
+}
+
+func openTest(t *testing.T, f *influx, batchSize int) *DB {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	prevBatch, prevFlush := BatchSize, FlushInterval
+	BatchSize, FlushInterval = batchSize, time.Hour
+	t.Cleanup(func() { BatchSize, FlushInterval = prevBatch, prevFlush })
+	return Open(srv.URL, "token", "org", "bucket")
+}
+
+func TestWriteBatches(t *testing.T) {
+	tests := []struct {
+		points    int
+		batchSize int
+		requests  int
+	}{
+		{0, 3, 0},
+		{1, 3, 1},
+		{3, 3, 1},
+		{7, 3, 3},
+		{10, 1, 10},
+	}
+	for _, tt := range tests {
+		f := &influx{}
+		db := openTest(t, f, tt.batchSize)
+		want := make([]string, tt.points)
+		for i := range want {
+			want[i] = "m,k=v f=" + strings.Repeat("1", i+1) + "i 1"
+			if err := db.Write(want[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.Flush(); err != nil {
+			t.Fatalf("%v points in batches of %v: Flush error: %v", tt.points, tt.batchSize, err)
+		}
+		if got := f.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%v points in batches of %v: wrote %q, want %q", tt.points, tt.batchSize, got, want)
+		}
+		if got := f.requests(); got != tt.requests {
+			t.Errorf("%v points in batches of %v: %v requests, want %v", tt.points, tt.batchSize, got, tt.requests)
+		}
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	f := &influx{failures: 1}
+	db := openTest(t, f, 10)
+	db.Write("m f=1i 1")
+	if err := db.Flush(); err == nil {
+		t.Error("Flush of a failed write returned no error")
+	}
+	if db.Err() == nil {
+		t.Error("Err is not set after a failed write")
+	}
+	db.Write("m f=2i 2")
+	if err := db.Flush(); err != nil {
+		t.Errorf("Flush after recovery error: %v", err)
+	}
+	if got := f.lines(); len(got) != 1 || got[0] != "m f=2i 2" {
+		t.Errorf("wrote %q after recovery", got)
+	}
+	db.Close()
+}
+
+func TestCloseFlushesAndRejects(t *testing.T) {
+	f := &influx{}
+	db := openTest(t, f, 100)
+	db.Write("m f=1i 1", "m f=2i 2")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.lines()); got != 2 {
+		t.Errorf("Close wrote %v points, want 2", got)
+	}
+	if err := db.Write("m f=3i 3"); err != ErrClosed {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+	if db.TryWrite("m f=3i 3") {
+		t.Error("TryWrite after Close succeeded")
+	}
+	if err := db.Flush(); err != ErrClosed {
+		t.Errorf("Flush after Close = %v, want ErrClosed", err)
+	}
+	if got := len(f.lines()); got != 2 {
+		t.Errorf("%v points written after Close", got-2)
+	}
+}
+
+func TestWriteTicksKeepsSharedSeconds(t *testing.T) {
+	f := &influx{}
+	db := openTest(t, f, 100)
+	second := time.Unix(1700000000, 0)
+	ticks := []ibgo.Tick{{Time: second, Last: 1, Size: 1}, {Time: second, Last: 2, Size: 1}, {Time: second, Last: 3, Size: 1}}
+	for i := 1; i < len(ticks); i++ {
+		ticks[i].SetShift(i)
+	}
+	if err := db.WriteTicks(&ibgo.Instrument{}, ticks); err != nil {
+		t.Fatal(err)
+	}
+	db.Flush()
+	seen := make(map[string]bool)
+	for _, l := range f.lines() {
+		ts := l[strings.LastIndex(l, " ")+1:]
+		if seen[ts] {
+			t.Errorf("timestamp %v written twice", ts)
+		}
+		seen[ts] = true
+	}
+	if len(seen) != len(ticks) {
+		t.Errorf("%v distinct points, want %v", len(seen), len(ticks))
+	}
+	db.Close()
+}
+
+func TestQueryBars(t *testing.T) {
+	f := &influx{csv: `#datatype,string,long,dateTime:RFC3339,double,double,double,double,long,double,long
+#group,false,false,false,false,false,false,false,false,false,false
+#default,_result,,,,,,,,,
+,result,table,_time,open,high,low,close,volume,wap,count
+,,0,2024-01-02T14:30:00Z,10,12,9,11,100,10.5,7
+,,0,2024-01-02T14:31:00Z,11,11.5,10.5,11.25,50,11.1,3
+
+`}
+	db := openTest(t, f, 100)
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	bars, err := db.QueryBars(&ibgo.Instrument{}, ibgo.Bar1Min, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ibgo.BarData{
+		{Time: time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC), Open: 10, High: 12, Low: 9, Close: 11, Volume: 100, WAP: 10.5, TradeCount: 7},
+		{Time: time.Date(2024, 1, 2, 14, 31, 0, 0, time.UTC), Open: 11, High: 11.5, Low: 10.5, Close: 11.25, Volume: 50, WAP: 11.1, TradeCount: 3},
+	}
+	if len(bars) != len(want) {
+		t.Fatalf("QueryBars returned %v bars, want %v", len(bars), len(want))
+	}
+	for i := range want {
+		if !bars[i].Time.Equal(want[i].Time) || bars[i].Open != want[i].Open || bars[i].High != want[i].High ||
+			bars[i].Low != want[i].Low || bars[i].Close != want[i].Close || bars[i].Volume != want[i].Volume ||
+			bars[i].WAP != want[i].WAP || bars[i].TradeCount != want[i].TradeCount {
+			t.Errorf("bar %v = %+v, want %+v", i, bars[i], want[i])
+		}
+	}
+	for _, part := range []string{`from(bucket: \"bucket\")`, `r.barSize == \"1 min\"`, "2024-01-02T00:00:00Z", "2024-01-03T00:00:00Z"} {
+		if !strings.Contains(f.query, part) {
+			t.Errorf("query %s does not contain %s", f.query, part)
+		}
+	}
+	db.Close()
+}
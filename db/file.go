@@ -0,0 +1,305 @@
+package db
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// FileSink archives bars and ticks under Dir with one file per instrument, kind and day:
+// Dir/<symbol>-<conID>/<ticks or bar size>/<yyyymmdd>.<ext>. Days follow the instrument's time zone.
+// CSV and JSONL files are flushed after every write and can be read while open. Parquet keeps its footer
+// until the file is rotated at the end of the day or Close is called, so a day being written is not readable yet
+type FileSink struct {
+	Dir    string
+	format fileFormat
+	mu     sync.Mutex
+	files  map[string]*dayFile
+}
+
+type fileFormat interface {
+	ext() string
+	appendable() bool
+	open(w io.Writer, fresh bool, bars bool) (recordWriter, error)
+}
+
+// recordWriter takes *ibgo.BarData or *ibgo.Tick
+type recordWriter interface {
+	write(rec interface{}) error
+	flush() error
+	close() error
+}
+
+type dayFile struct {
+	day string
+	f   *os.File
+	rw  recordWriter
+}
+
+// NewCSVSink writes BarData.ToCSV and Tick.ToCSV records under a header row
+func NewCSVSink(dir string) *FileSink {
+	return &FileSink{Dir: dir, format: csvFormat{}, files: make(map[string]*dayFile)}
+}
+
+// NewJSONLSink writes one JSON object per line, ticks carry millisecond epoch times
+func NewJSONLSink(dir string) *FileSink {
+	return &FileSink{Dir: dir, format: jsonlFormat{}, files: make(map[string]*dayFile)}
+}
+
+// NewParquetSink writes snappy compressed Parquet files. A file is only readable once rotated or closed,
+// and a day reopened after that goes to a new numbered file
+func NewParquetSink(dir string) *FileSink {
+	return &FileSink{Dir: dir, format: parquetFormat{}, files: make(map[string]*dayFile)}
+}
+
+func (s *FileSink) WriteBars(ins *ibgo.Instrument, barSize ibgo.BarSize, bars []ibgo.BarData) error {
+	kind := strings.Replace(string(barSize), " ", "", -1)
+	return s.write(ins, kind, true, len(bars), func(i int) (time.Time, interface{}) {
+		return bars[i].Time, &bars[i]
+	})
+}
+
+func (s *FileSink) WriteTicks(ins *ibgo.Instrument, ticks []ibgo.Tick) error {
+	return s.write(ins, "ticks", false, len(ticks), func(i int) (time.Time, interface{}) {
+		return ticks[i].Time, &ticks[i]
+	})
+}
+
+func (s *FileSink) write(ins *ibgo.Instrument, kind string, bars bool, n int, at func(i int) (time.Time, interface{})) (err error) {
+	loc, err := time.LoadLocation(ins.Detail.TimeZoneID)
+	if err != nil {
+		loc = time.UTC
+	}
+	con := ins.Contract()
+	dir := filepath.Join(s.Dir, fmt.Sprintf("%v-%v", con.Symbol, con.ConID), kind)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var df *dayFile
+	for i := 0; i < n; i++ {
+		t, rec := at(i)
+		if day := t.In(loc).Format("20060102"); df == nil || df.day != day {
+			if df != nil {
+				if err = df.rw.flush(); err != nil {
+					return
+				}
+			}
+			if df, err = s.rotate(dir, day, bars); err != nil {
+				return
+			}
+		}
+		if err = df.rw.write(rec); err != nil {
+			return
+		}
+	}
+	if df != nil {
+		err = df.rw.flush()
+	}
+	return
+}
+
+// rotate returns the open file of dir for day, closing the one of an earlier day
+func (s *FileSink) rotate(dir string, day string, bars bool) (df *dayFile, err error) {
+	if df = s.files[dir]; df != nil {
+		if df.day == day {
+			return
+		}
+		delete(s.files, dir)
+		if err = df.close(); err != nil {
+			return nil, err
+		}
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	path := filepath.Join(dir, day+"."+s.format.ext())
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if s.format.appendable() {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	} else {
+		for i := 1; ; i++ {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				break
+			}
+			path = filepath.Join(dir, fmt.Sprintf("%v-%v.%v", day, i, s.format.ext()))
+		}
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return
+	}
+	rw, err := s.format.open(f, info.Size() == 0, bars)
+	if err != nil {
+		f.Close()
+		return
+	}
+	df = &dayFile{day, f, rw}
+	s.files[dir] = df
+	return
+}
+
+func (df *dayFile) close() error {
+	if err := df.rw.close(); err != nil {
+		df.f.Close()
+		return err
+	}
+	return df.f.Close()
+}
+
+// Close finishes every open file
+func (s *FileSink) Close() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for dir, df := range s.files {
+		if e := df.close(); e != nil && err == nil {
+			err = e
+		}
+		delete(s.files, dir)
+	}
+	return
+}
+
+type csvFormat struct{}
+
+func (csvFormat) ext() string      { return "csv" }
+func (csvFormat) appendable() bool { return true }
+
+func (csvFormat) open(w io.Writer, fresh bool, bars bool) (recordWriter, error) {
+	cw := csv.NewWriter(w)
+	if fresh {
+		header := ibgo.TickCSVHeader
+		if bars {
+			header = ibgo.BarCSVHeader
+		}
+		if err := cw.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	return csvWriter{cw}, nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw csvWriter) write(rec interface{}) error {
+	switch r := rec.(type) {
+	case *ibgo.BarData:
+		return cw.w.Write(r.ToCSV())
+	case *ibgo.Tick:
+		return cw.w.Write(r.ToCSV())
+	}
+	return fmt.Errorf("Unknown record %T", rec)
+}
+
+func (cw csvWriter) flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw csvWriter) close() error {
+	return cw.flush()
+}
+
+type jsonlFormat struct{}
+
+func (jsonlFormat) ext() string      { return "jsonl" }
+func (jsonlFormat) appendable() bool { return true }
+
+func (jsonlFormat) open(w io.Writer, fresh bool, bars bool) (recordWriter, error) {
+	return jsonlWriter{json.NewEncoder(w)}, nil
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (jw jsonlWriter) write(rec interface{}) error {
+	return jw.enc.Encode(rec)
+}
+
+func (jw jsonlWriter) flush() error {
+	return nil
+}
+
+func (jw jsonlWriter) close() error {
+	return nil
+}
+
+type parquetBar struct {
+	Time       int64   `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Open       float64 `parquet:"name=open, type=DOUBLE"`
+	High       float64 `parquet:"name=high, type=DOUBLE"`
+	Low        float64 `parquet:"name=low, type=DOUBLE"`
+	Close      float64 `parquet:"name=close, type=DOUBLE"`
+	Volume     int64   `parquet:"name=volume, type=INT64"`
+	WAP        float64 `parquet:"name=wap, type=DOUBLE"`
+	TradeCount int64   `parquet:"name=count, type=INT64"`
+}
+
+type parquetTick struct {
+	Time              int64   `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	Last              float64 `parquet:"name=last, type=DOUBLE"`
+	Size              int64   `parquet:"name=size, type=INT64"`
+	Bid               float64 `parquet:"name=bid, type=DOUBLE"`
+	Ask               float64 `parquet:"name=ask, type=DOUBLE"`
+	BidSize           int64   `parquet:"name=bidsize, type=INT64"`
+	AskSize           int64   `parquet:"name=asksize, type=INT64"`
+	Midpoint          float64 `parquet:"name=midpoint, type=DOUBLE"`
+	Mask              int64   `parquet:"name=mask, type=INT64"`
+	Exchange          string  `parquet:"name=exchange, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SpecialConditions string  `parquet:"name=conditions, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+type parquetFormat struct{}
+
+func (parquetFormat) ext() string      { return "parquet" }
+func (parquetFormat) appendable() bool { return false }
+
+func (parquetFormat) open(w io.Writer, fresh bool, bars bool) (recordWriter, error) {
+	var schema interface{} = new(parquetTick)
+	if bars {
+		schema = new(parquetBar)
+	}
+	pw, err := writer.NewParquetWriterFromWriter(w, schema, 1)
+	if err != nil {
+		return nil, err
+	}
+	return parquetWriter{pw}, nil
+}
+
+type parquetWriter struct {
+	pw *writer.ParquetWriter
+}
+
+func (pw parquetWriter) write(rec interface{}) error {
+	switch r := rec.(type) {
+	case *ibgo.BarData:
+		return pw.pw.Write(parquetBar{r.Time.UnixNano() / int64(time.Millisecond), r.Open, r.High, r.Low, r.Close, r.Volume, r.WAP, r.TradeCount})
+	case *ibgo.Tick:
+		return pw.pw.Write(parquetTick{r.Time.UnixNano() / int64(time.Microsecond), r.Last, r.Size, r.Bid, r.Ask, r.BidSize, r.AskSize, r.Midpoint, r.Mask, r.Exchange, r.SpecialConditions})
+	}
+	return fmt.Errorf("Unknown record %T", rec)
+}
+
+// flush is left to the row groups. Flushed row groups are not readable without the footer written on close
+func (pw parquetWriter) flush() error {
+	return nil
+}
+
+func (pw parquetWriter) close() error {
+	return pw.pw.WriteStop()
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// newYorkInstrument has no contract, so its files go under "-0"
+func newYorkInstrument(t *testing.T) (*ibgo.Instrument, *time.Location) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	ins := &ibgo.Instrument{}
+	ins.Detail.TimeZoneID = "America/New_York"
+	return ins, loc
+}
+
+// files lists the files of kind written for the instrument without a contract
+func files(t *testing.T, dir string, kind string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "-0", kind, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = filepath.Base(m)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sinkTicks and sinkBars cross midnight in New York, which is still the same day in UTC
+func sinkTicks(loc *time.Location) []ibgo.Tick {
+	return []ibgo.Tick{
+		{Time: time.Date(2024, 1, 2, 23, 59, 0, 0, loc), Last: 1, Size: 10, Exchange: "NYSE"},
+		{Time: time.Date(2024, 1, 2, 23, 59, 30, 0, loc), Last: 2, Size: 20},
+		{Time: time.Date(2024, 1, 3, 0, 0, 30, 0, loc), Last: 3, Size: 30},
+	}
+}
+
+func sinkBars(loc *time.Location) []ibgo.BarData {
+	return []ibgo.BarData{
+		{Time: time.Date(2024, 1, 2, 23, 59, 0, 0, loc), Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 10, WAP: 1.25, TradeCount: 2},
+		{Time: time.Date(2024, 1, 3, 0, 0, 0, 0, loc), Open: 3, High: 4, Low: 2.5, Close: 3.5, Volume: 30, WAP: 3.25, TradeCount: 4},
+	}
+}
+
+func TestFileSinkRotatesInInstrumentZone(t *testing.T) {
+	ins, loc := newYorkInstrument(t)
+	tests := []struct {
+		sink *FileSink
+		ext  string
+	}{
+		{NewCSVSink(t.TempDir()), "csv"},
+		{NewJSONLSink(t.TempDir()), "jsonl"},
+		{NewParquetSink(t.TempDir()), "parquet"},
+	}
+	for _, tt := range tests {
+		if err := tt.sink.WriteTicks(ins, sinkTicks(loc)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.sink.WriteBars(ins, ibgo.Bar1Min, sinkBars(loc)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"20240102." + tt.ext, "20240103." + tt.ext}
+		for _, kind := range []string{"ticks", "1min"} {
+			if got := files(t, tt.sink.Dir, kind); strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("%v %v files %v, want %v", tt.ext, kind, got, want)
+			}
+		}
+	}
+}
+
+func TestCSVSink(t *testing.T) {
+	ins, loc := newYorkInstrument(t)
+	sink := NewCSVSink(t.TempDir())
+	ticks := sinkTicks(loc)
+	// a day written again after Close is appended to without a second header
+	sink.WriteTicks(ins, ticks[:1])
+	sink.Close()
+	sink.WriteTicks(ins, ticks[1:2])
+	sink.WriteBars(ins, ibgo.Bar1Min, sinkBars(loc))
+	sink.Close()
+	f, err := os.Open(filepath.Join(sink.Dir, "-0", "ticks", "20240102.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{ibgo.TickCSVHeader, ticks[0].ToCSV(), ticks[1].ToCSV()}
+	if len(records) != len(want) {
+		t.Fatalf("read %v records, want %v", len(records), len(want))
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("record %v = %v, want %v", i, records[i], want[i])
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(sink.Dir, "-0", "1min", "20240103.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar := sinkBars(loc)[1]
+	if got, want := string(b), strings.Join(ibgo.BarCSVHeader, ",")+"\n"+strings.Join(bar.ToCSV(), ",")+"\n"; got != want {
+		t.Errorf("bar file %q, want %q", got, want)
+	}
+}
+
+func TestJSONLSink(t *testing.T) {
+	ins, loc := newYorkInstrument(t)
+	sink := NewJSONLSink(t.TempDir())
+	sink.WriteTicks(ins, sinkTicks(loc))
+	sink.WriteBars(ins, ibgo.Bar1Min, sinkBars(loc))
+	sink.Close()
+	b, err := ioutil.ReadFile(filepath.Join(sink.Dir, "-0", "ticks", "20240102.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%v tick lines, want 2", len(lines))
+	}
+	for i, l := range lines {
+		got := struct {
+			Time     int64
+			Last     float64
+			Size     int64
+			Exchange string
+		}{}
+		if err := json.Unmarshal([]byte(l), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := sinkTicks(loc)[i]
+		if got.Time != want.Time.UnixNano()/int64(time.Millisecond) || got.Last != want.Last || got.Size != want.Size || got.Exchange != want.Exchange {
+			t.Errorf("tick line %v = %+v, want %+v", i, got, want)
+		}
+	}
+	b, err = ioutil.ReadFile(filepath.Join(sink.Dir, "-0", "1min", "20240103.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar := ibgo.BarData{}
+	if err := json.Unmarshal(b, &bar); err != nil {
+		t.Fatal(err)
+	}
+	if want := sinkBars(loc)[1]; !bar.Time.Equal(want.Time) || bar.Close != want.Close || bar.Volume != want.Volume || bar.WAP != want.WAP {
+		t.Errorf("bar %+v, want %+v", bar, want)
+	}
+}
+
+func readParquet(t *testing.T, path string, rows interface{}, schema interface{}) {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pf, err := buffer.NewBufferFile(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := reader.NewParquetReader(pf, schema, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+	if err := pr.Read(rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParquetSink(t *testing.T) {
+	ins, loc := newYorkInstrument(t)
+	sink := NewParquetSink(t.TempDir())
+	ticks := sinkTicks(loc)
+	sink.WriteTicks(ins, ticks)
+	sink.WriteBars(ins, ibgo.Bar1Min, sinkBars(loc))
+	sink.Close()
+	// a day written again after Close goes to a numbered file
+	sink.WriteTicks(ins, ticks[2:])
+	sink.Close()
+	if got := files(t, sink.Dir, "ticks"); strings.Join(got, " ") != "20240102.parquet 20240103-1.parquet 20240103.parquet" {
+		t.Errorf("tick files %v", got)
+	}
+	got := make([]parquetTick, 2)
+	readParquet(t, filepath.Join(sink.Dir, "-0", "ticks", "20240102.parquet"), &got, new(parquetTick))
+	for i, p := range got {
+		want := ticks[i]
+		if p.Time != want.Time.UnixNano()/int64(time.Microsecond) || p.Last != want.Last || p.Size != want.Size || p.Exchange != want.Exchange {
+			t.Errorf("tick row %v = %+v, want %+v", i, p, want)
+		}
+	}
+	bars := make([]parquetBar, 1)
+	readParquet(t, filepath.Join(sink.Dir, "-0", "1min", "20240102.parquet"), &bars, new(parquetBar))
+	if want := sinkBars(loc)[0]; bars[0].Time != want.Time.UnixNano()/int64(time.Millisecond) || bars[0].Open != want.Open ||
+		bars[0].Close != want.Close || bars[0].Volume != want.Volume || bars[0].TradeCount != want.TradeCount {
+		t.Errorf("bar row %+v, want %+v", bars[0], want)
+	}
+}
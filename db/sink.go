@@ -0,0 +1,104 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// Sink stores the bars and ticks of instruments. DB and the file sinks all satisfy it
+type Sink interface {
+	WriteBars(ins *ibgo.Instrument, barSize ibgo.BarSize, bars []ibgo.BarData) error
+	WriteTicks(ins *ibgo.Instrument, ticks []ibgo.Tick) error
+	Close() error
+}
+
+// TeeTicks returns a stream carrying the same ticks as stream after each is written to sink.
+// The first sink error cancels stream and ends the returned one, Err holds it once Ticks is closed
+func TeeTicks(sink Sink, ins *ibgo.Instrument, stream *ibgo.TickStream) *ibgo.TickStream {
+	tee := &ibgo.TickStream{Ticks: make(chan ibgo.Tick), Params: stream.Params}
+	done := make(chan struct{})
+	var once sync.Once
+	tee.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return stream.Cancel()
+	}
+	go func() {
+		var err error
+		defer func() {
+			tee.Err = err
+			close(tee.Ticks)
+		}()
+		for t := range stream.Ticks {
+			if err = sink.WriteTicks(ins, []ibgo.Tick{t}); err != nil {
+				stream.Cancel()
+				// the source only ends once its ticks are read
+				for range stream.Ticks {
+				}
+				return
+			}
+			select {
+			case tee.Ticks <- t:
+			case <-done:
+			}
+		}
+		err = stream.Err
+	}()
+	return tee
+}
+
+// TeeBars returns a stream carrying the same bars as stream after each is written to sink.
+// The first sink error cancels stream and ends the returned one, Err holds it once Bars is closed
+func TeeBars(sink Sink, ins *ibgo.Instrument, barSize ibgo.BarSize, stream *ibgo.BarStream) *ibgo.BarStream {
+	tee := &ibgo.BarStream{Bars: make(chan ibgo.BarData)}
+	done := make(chan struct{})
+	var once sync.Once
+	tee.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return stream.Cancel()
+	}
+	go func() {
+		var err error
+		defer func() {
+			tee.Err = err
+			close(tee.Bars)
+		}()
+		for bar := range stream.Bars {
+			if err = sink.WriteBars(ins, barSize, []ibgo.BarData{bar}); err != nil {
+				stream.Cancel()
+				// the source only ends once its bars are read
+				for range stream.Bars {
+				}
+				return
+			}
+			select {
+			case tee.Bars <- bar:
+			case <-done:
+			}
+		}
+		err = stream.Err
+	}()
+	return tee
+}
+
+// TickWriter returns a DownloadTicks callback that writes every tick to sink
+func TickWriter(sink Sink, ins *ibgo.Instrument) func(ibgo.Tick) error {
+	return func(t ibgo.Tick) error {
+		return sink.WriteTicks(ins, []ibgo.Tick{t})
+	}
+}
+
+// SaveBars downloads the bars of ins in [start, end) and writes them to sink
+func SaveBars(sink Sink, ins *ibgo.Instrument, start time.Time, end time.Time, barSize ibgo.BarSize, whatToShow ibgo.WhatToShow, useRTH bool) error {
+	bars, err := ins.DownloadBars(start, end, barSize, whatToShow, useRTH)
+	if err != nil {
+		return err
+	}
+	return sink.WriteBars(ins, barSize, bars)
+}
+
+// SaveTicks downloads the ticks of ins in [start, end) and writes them to sink
+func SaveTicks(sink Sink, ins *ibgo.Instrument, start time.Time, end time.Time, whatToShow ibgo.WhatToShow, useRTH bool) error {
+	return ins.DownloadTicks(start, end, whatToShow, useRTH, TickWriter(sink, ins))
+}
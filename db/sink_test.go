@@ -0,0 +1,181 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// failSink fails every write from the nth on
+type failSink struct {
+	n       int
+	written []ibgo.Tick
+	bars    []ibgo.BarData
+}
+
+var errSink = errors.New("sink failed")
+
+func (s *failSink) WriteTicks(ins *ibgo.Instrument, ticks []ibgo.Tick) error {
+	if len(s.written) >= s.n {
+		return errSink
+	}
+	s.written = append(s.written, ticks...)
+	return nil
+}
+
+func (s *failSink) WriteBars(ins *ibgo.Instrument, barSize ibgo.BarSize, bars []ibgo.BarData) error {
+	if len(s.bars) >= s.n {
+		return errSink
+	}
+	s.bars = append(s.bars, bars...)
+	return nil
+}
+
+func (s *failSink) Close() error {
+	return nil
+}
+
+// source streams n ticks and then blocks until cancelled, like a live stream would
+func source(n int) (stream *ibgo.TickStream, cancelled chan struct{}) {
+	stream = &ibgo.TickStream{Ticks: make(chan ibgo.Tick), Params: func() *ibgo.TickReqParams { return nil }}
+	cancelled = make(chan struct{})
+	var once sync.Once
+	stream.Cancel = func() error {
+		once.Do(func() { close(cancelled) })
+		return nil
+	}
+	go func() {
+		defer close(stream.Ticks)
+		for i := 0; ; i++ {
+			t := ibgo.Tick{Time: time.Unix(int64(i), 0), Last: 1}
+			if i >= n {
+				<-cancelled
+				return
+			}
+			select {
+			case stream.Ticks <- t:
+			case <-cancelled:
+				return
+			}
+		}
+	}()
+	return
+}
+
+func TestTeeTicks(t *testing.T) {
+	tests := []struct {
+		ticks   int
+		failAt  int
+		forward int
+		err     error
+	}{
+		{5, 10, 5, nil},
+		{5, 0, 0, errSink},
+		{5, 3, 3, errSink},
+	}
+	for _, tt := range tests {
+		stream, cancelled := source(tt.ticks)
+		sink := &failSink{n: tt.failAt}
+		tee := TeeTicks(sink, &ibgo.Instrument{}, stream)
+		got := 0
+		for range tee.Ticks {
+			got++
+			if got == tt.ticks && tt.err == nil {
+				tee.Cancel()
+			}
+		}
+		if got != tt.forward {
+			t.Errorf("%v ticks failing at %v: forwarded %v, want %v", tt.ticks, tt.failAt, got, tt.forward)
+		}
+		if tee.Err != tt.err {
+			t.Errorf("%v ticks failing at %v: Err = %v, want %v", tt.ticks, tt.failAt, tee.Err, tt.err)
+		}
+		select {
+		case <-cancelled:
+		default:
+			t.Errorf("%v ticks failing at %v: source not cancelled", tt.ticks, tt.failAt)
+		}
+	}
+}
+
+func TestTeeTicksCancelWhileBlocked(t *testing.T) {
+	stream, _ := source(100)
+	tee := TeeTicks(&failSink{n: 100}, &ibgo.Instrument{}, stream)
+	<-tee.Ticks
+	tee.Cancel()
+	done := make(chan struct{})
+	go func() {
+		for range tee.Ticks {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("tee did not end after Cancel")
+	}
+}
+
+// barSource streams n bars and then blocks until cancelled, like an aggregated live stream would
+func barSource(n int) (stream *ibgo.BarStream, cancelled chan struct{}) {
+	stream = &ibgo.BarStream{Bars: make(chan ibgo.BarData)}
+	cancelled = make(chan struct{})
+	var once sync.Once
+	stream.Cancel = func() error {
+		once.Do(func() { close(cancelled) })
+		return nil
+	}
+	go func() {
+		defer close(stream.Bars)
+		for i := 0; ; i++ {
+			if i >= n {
+				<-cancelled
+				return
+			}
+			select {
+			case stream.Bars <- ibgo.BarData{Time: time.Unix(int64(i), 0), Close: 1}:
+			case <-cancelled:
+				return
+			}
+		}
+	}()
+	return
+}
+
+func TestTeeBars(t *testing.T) {
+	tests := []struct {
+		bars    int
+		failAt  int
+		forward int
+		err     error
+	}{
+		{5, 10, 5, nil},
+		{5, 0, 0, errSink},
+		{5, 3, 3, errSink},
+	}
+	for _, tt := range tests {
+		stream, cancelled := barSource(tt.bars)
+		tee := TeeBars(&failSink{n: tt.failAt}, &ibgo.Instrument{}, ibgo.Bar1Min, stream)
+		got := 0
+		for range tee.Bars {
+			got++
+			if got == tt.bars && tt.err == nil {
+				tee.Cancel()
+			}
+		}
+		if got != tt.forward {
+			t.Errorf("%v bars failing at %v: forwarded %v, want %v", tt.bars, tt.failAt, got, tt.forward)
+		}
+		if tee.Err != tt.err {
+			t.Errorf("%v bars failing at %v: Err = %v, want %v", tt.bars, tt.failAt, tee.Err, tt.err)
+		}
+		select {
+		case <-cancelled:
+		default:
+			t.Errorf("%v bars failing at %v: source not cancelled", tt.bars, tt.failAt)
+		}
+	}
+}
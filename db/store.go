@@ -0,0 +1,268 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// Series names what a Store holds of an instrument: ticks of WhatToShow, or bars when BarSize is set.
+// UseRTH limits both the data and the sessions gaps are looked for in to liquid hours
+type Series struct {
+	WhatToShow ibgo.WhatToShow
+	BarSize    ibgo.BarSize
+	UseRTH     bool
+}
+
+func (s Series) key(ins *ibgo.Instrument) string {
+	return fmt.Sprintf("%v|%v|%v|%v", ins.ConID(), s.WhatToShow, s.BarSize, s.UseRTH)
+}
+
+type Range struct {
+	Start time.Time
+	End   time.Time
+}
+
+// RecordMarkInterval is how often a recorded stream extends the held range
+var RecordMarkInterval = time.Second * 10
+
+// Store keeps a local history in Sink and remembers which ranges of each series it holds, so that gaps left by
+// disconnects or missed sessions can be found and backfilled. The ranges are written through to a JSON file
+type Store struct {
+	Sink   Sink
+	path   string
+	mu     sync.Mutex
+	ranges map[string][]Range
+}
+
+// OpenStore loads the ranges held in path. An empty path keeps them in memory
+func OpenStore(sink Sink, path string) (*Store, error) {
+	s := &Store{Sink: sink, path: path, ranges: make(map[string][]Range)}
+	if path == "" {
+		return s, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.ranges); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Held returns the ranges of series held for ins, ordered and merged
+func (s *Store) Held(ins *ibgo.Instrument, series Series) []Range {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Range(nil), s.ranges[series.key(ins)]...)
+}
+
+// Mark records r of series as held for ins
+func (s *Store) Mark(ins *ibgo.Instrument, series Series, r Range) error {
+	if !r.Start.Before(r.End) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := series.key(ins)
+	s.ranges[key] = mergeRanges(append(s.ranges[key], r))
+	return s.save()
+}
+
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(s.ranges)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func mergeRanges(ranges []Range) []Range {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start.Before(ranges[j].Start) })
+	merged := make([]Range, 0, len(ranges))
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && !r.Start.After(merged[n-1].End) {
+			if r.End.After(merged[n-1].End) {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Gaps returns the parts of [start, end) within sessions of ins that series does not hold. Outside the span
+// IB published sessions for they follow its weekly pattern; with no sessions known all of [start, end) is taken
+func (s *Store) Gaps(ins *ibgo.Instrument, series Series, start time.Time, end time.Time) []Range {
+	need := []Range{{start, end}}
+	if sessions, err := ins.Sessions(start, end, series.UseRTH); err != ibgo.ErrNoSession {
+		need = tradingRanges(sessions, start, end)
+	}
+	return subtractRanges(need, s.Held(ins, series))
+}
+
+// tradingRanges clips the ordered sessions to [start, end)
+func tradingRanges(sessions []ibgo.Session, start time.Time, end time.Time) []Range {
+	ranges := make([]Range, 0, len(sessions))
+	for _, session := range sessions {
+		r := Range{session.Start, session.End}
+		if r.Start.Before(start) {
+			r.Start = start
+		}
+		if r.End.After(end) {
+			r.End = end
+		}
+		if r.Start.Before(r.End) {
+			ranges = append(ranges, r)
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// subtractRanges returns what of the ordered ranges in need is not covered by the ordered ranges in held
+func subtractRanges(need []Range, held []Range) []Range {
+	gaps := make([]Range, 0)
+	j := 0
+	for _, r := range need {
+		from := r.Start
+		for j < len(held) && !held[j].End.After(from) {
+			j++
+		}
+		for k := j; k < len(held) && held[k].Start.Before(r.End); k++ {
+			if held[k].Start.After(from) {
+				gaps = append(gaps, Range{from, held[k].Start})
+			}
+			if held[k].End.After(from) {
+				from = held[k].End
+			}
+		}
+		if from.Before(r.End) {
+			gaps = append(gaps, Range{from, r.End})
+		}
+	}
+	return gaps
+}
+
+// BackfillLag is how far behind the present Backfill stops, leaving time for IB to publish the latest ticks
+var BackfillLag = time.Minute
+
+// Backfill downloads every gap of series in [start, end) into Sink and marks it held. end is capped at
+// BackfillLag before now, and bars still forming by then are left out, so that only complete data is marked
+func (s *Store) Backfill(ins *ibgo.Instrument, series Series, start time.Time, end time.Time) error {
+	settled := time.Now().Add(-BackfillLag)
+	if end.After(settled) {
+		end = settled
+	}
+	for _, gap := range s.Gaps(ins, series, start, end) {
+		var err error
+		if series.BarSize != "" {
+			var bars []ibgo.BarData
+			bars, err = ins.DownloadBars(gap.Start, gap.End, series.BarSize, series.WhatToShow, series.UseRTH)
+			if err == nil {
+				bars, gap.End, err = completeBars(bars, series.BarSize, gap.End, settled)
+			}
+			if err == nil && len(bars) > 0 {
+				err = s.Sink.WriteBars(ins, series.BarSize, bars)
+			}
+		} else {
+			err = ins.DownloadTicks(gap.Start, gap.End, series.WhatToShow, series.UseRTH, TickWriter(s.Sink, ins))
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.Mark(ins, series, gap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completeBars drops the bars that had not ended by settled and returns how far the rest covers up to end
+func completeBars(bars []ibgo.BarData, barSize ibgo.BarSize, end time.Time, settled time.Time) ([]ibgo.BarData, time.Time, error) {
+	barLen, err := barSize.Length()
+	if err != nil {
+		return nil, end, err
+	}
+	for i, bar := range bars {
+		if bar.Time.Add(barLen).After(settled) {
+			if bar.Time.Before(end) {
+				end = bar.Time
+			}
+			return bars[:i], end, nil
+		}
+	}
+	return bars, end, nil
+}
+
+// KeepComplete backfills series from start up to now at once and then every interval until stop is called.
+// Errors are passed to onErr and retried on the next round
+func (s *Store) KeepComplete(ins *ibgo.Instrument, series Series, start time.Time, interval time.Duration, onErr func(error)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Backfill(ins, series, start, time.Now()); err != nil && onErr != nil {
+				onErr(err)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Record writes the ticks of a live stream to Sink and marks the time it stays connected as held,
+// so that only what was missed while disconnected is left for Backfill
+func (s *Store) Record(ins *ibgo.Instrument, series Series, stream *ibgo.TickStream) *ibgo.TickStream {
+	now := time.Now()
+	return TeeTicks(&recorder{s, series, now, now}, ins, stream)
+}
+
+// recorder is the Sink Record tees into
+type recorder struct {
+	store  *Store
+	series Series
+	start  time.Time
+	marked time.Time
+}
+
+func (r *recorder) WriteTicks(ins *ibgo.Instrument, ticks []ibgo.Tick) error {
+	if err := r.store.Sink.WriteTicks(ins, ticks); err != nil {
+		return err
+	}
+	if now := time.Now(); now.Sub(r.marked) >= RecordMarkInterval {
+		r.marked = now
+		return r.store.Mark(ins, r.series, Range{r.start, now})
+	}
+	return nil
+}
+
+func (r *recorder) WriteBars(ins *ibgo.Instrument, barSize ibgo.BarSize, bars []ibgo.BarData) error {
+	return r.store.Sink.WriteBars(ins, barSize, bars)
+}
+
+func (r *recorder) Close() error {
+	return nil
+}
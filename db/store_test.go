@@ -0,0 +1,150 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+var epoch = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// at returns epoch plus h hours
+func at(h int) time.Time {
+	return epoch.Add(time.Duration(h) * time.Hour)
+}
+
+// rs builds ranges from pairs of hours
+func rs(hours ...int) []Range {
+	ranges := make([]Range, 0, len(hours)/2)
+	for i := 0; i+1 < len(hours); i += 2 {
+		ranges = append(ranges, Range{at(hours[i]), at(hours[i+1])})
+	}
+	return ranges
+}
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		in   []Range
+		want []Range
+	}{
+		{rs(), rs()},
+		{rs(1, 2), rs(1, 2)},
+		{rs(1, 2, 3, 4), rs(1, 2, 3, 4)},
+		{rs(3, 4, 1, 2), rs(1, 2, 3, 4)},
+		{rs(1, 3, 2, 4), rs(1, 4)},
+		{rs(1, 2, 2, 3), rs(1, 3)},
+		{rs(1, 5, 2, 3), rs(1, 5)},
+		{rs(5, 6, 1, 2, 2, 4, 3, 5), rs(1, 6)},
+	}
+	for _, tt := range tests {
+		in := append([]Range(nil), tt.in...)
+		if got := mergeRanges(in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mergeRanges(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSubtractRanges(t *testing.T) {
+	tests := []struct {
+		need []Range
+		held []Range
+		want []Range
+	}{
+		{rs(0, 10), rs(), rs(0, 10)},
+		{rs(0, 10), rs(0, 10), rs()},
+		{rs(0, 10), rs(-5, 15), rs()},
+		{rs(0, 10), rs(2, 4), rs(0, 2, 4, 10)},
+		{rs(0, 10), rs(-2, 4), rs(4, 10)},
+		{rs(0, 10), rs(6, 12), rs(0, 6)},
+		{rs(0, 10), rs(1, 2, 4, 5, 8, 9), rs(0, 1, 2, 4, 5, 8, 9, 10)},
+		{rs(0, 4, 6, 10), rs(3, 7), rs(0, 3, 7, 10)},
+		{rs(0, 4, 6, 10), rs(4, 6), rs(0, 4, 6, 10)},
+		{rs(0, 2, 6, 8), rs(-3, -1, 2, 6, 9, 12), rs(0, 2, 6, 8)},
+		{rs(), rs(0, 10), rs()},
+	}
+	for _, tt := range tests {
+		if got := subtractRanges(tt.need, tt.held); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("subtractRanges(%v, %v) = %v, want %v", tt.need, tt.held, got, tt.want)
+		}
+	}
+}
+
+func TestTradingRanges(t *testing.T) {
+	sessions := []ibgo.Session{{Start: at(2), End: at(4)}, {Start: at(6), End: at(8)}}
+	tests := []struct {
+		sessions   []ibgo.Session
+		start, end int
+		want       []Range
+	}{
+		{nil, 0, 10, rs()},
+		{sessions, 3, 7, rs(3, 4, 6, 7)},
+		{sessions, 4, 6, rs()},
+		{sessions, 0, 3, rs(2, 3)},
+		{sessions, 7, 12, rs(7, 8)},
+		{sessions, 0, 12, rs(2, 4, 6, 8)},
+	}
+	for _, tt := range tests {
+		if got := tradingRanges(tt.sessions, at(tt.start), at(tt.end)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tradingRanges(%v, %v, %v) = %v, want %v", tt.sessions, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestGaps(t *testing.T) {
+	store, _ := OpenStore(nil, "")
+	// IB published a single Tuesday session, from 02:00 to 04:00 UTC
+	ins := &ibgo.Instrument{}
+	ins.Detail.TimeZoneID = "UTC"
+	ins.Detail.TradingHours = []ibgo.Session{{Start: at(2), End: at(4)}}
+	store.Mark(ins, Series{}, Range{at(2), at(3)})
+	tests := []struct {
+		start, end int
+		want       []Range
+	}{
+		{0, 24, rs(3, 4)},
+		{3, 24, rs(3, 4)},
+		{5, 24, rs()},
+		// the Tuesday before follows the weekly pattern
+		{-7 * 24, -6 * 24, rs(-7*24+2, -7*24+4)},
+	}
+	for _, tt := range tests {
+		if got := store.Gaps(ins, Series{}, at(tt.start), at(tt.end)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Gaps(%v, %v) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+	// with no sessions known all of the range is needed
+	store, _ = OpenStore(nil, "")
+	if got, want := store.Gaps(&ibgo.Instrument{}, Series{}, at(0), at(5)), rs(0, 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("Gaps without sessions = %v, want %v", got, want)
+	}
+}
+
+func TestCompleteBars(t *testing.T) {
+	bars := []ibgo.BarData{{Time: at(0)}, {Time: at(1)}, {Time: at(2)}}
+	tests := []struct {
+		end, settled int
+		n            int
+		covered      int
+	}{
+		{3, 5, 3, 3},
+		{3, 3, 3, 3},
+		{3, 2, 2, 2},
+		{5, 2, 2, 2},
+		{3, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		got, covered, err := completeBars(bars, ibgo.Bar1Hour, at(tt.end), at(tt.settled))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != tt.n || !covered.Equal(at(tt.covered)) {
+			t.Errorf("completeBars up to %v settled at %v = %v bars covering to %v, want %v covering to %v",
+				tt.end, tt.settled, len(got), covered.Sub(epoch), tt.n, at(tt.covered).Sub(epoch))
+		}
+	}
+	if _, _, err := completeBars(bars, "7 mins", at(3), at(3)); err == nil {
+		t.Error("completeBars accepted an unknown bar size")
+	}
+}
@@ -1,7 +1,9 @@
 package ibgo
 
 import (
+	"strconv"
 	"strings"
+	"time"
 )
 
 func decodeNextValidID(m *Message, rd *msgReader) {
@@ -81,6 +83,59 @@ func decodeContractData(m *Message, rd *msgReader) {
 	c.RealExpirationDate = rd.readString() //serverVersion 134
 }
 
+func decodeBondContractData(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	rd.discard()
+	m.id = rd.readString()
+	c := &BondContractData{}
+	m.body = c
+	c.Symbol = rd.readString()
+	c.SecType = rd.readString()
+	c.CUSIP = rd.readString()
+	c.Coupon = rd.readFloat()
+	if sl := strings.Split(rd.readString(), " "); len(sl) > 0 {
+		c.Maturity = sl[0]
+		if len(sl) > 1 {
+			c.LastTradeTime = sl[1]
+		}
+		if len(sl) > 2 {
+			c.TimeZoneID = sl[2]
+		}
+	}
+	c.IssueDate = rd.readString()
+	c.Ratings = rd.readString()
+	c.BondType = rd.readString()
+	c.CouponType = rd.readString()
+	c.Convertible = rd.readBool()
+	c.Callable = rd.readBool()
+	c.Putable = rd.readBool()
+	c.DescAppend = rd.readString()
+	c.Exchange = rd.readString()
+	c.Currency = rd.readString()
+	c.MarketName = rd.readString()
+	c.TradingClass = rd.readString()
+	c.ConID = rd.readInt()
+	c.MinTick = rd.readFloat()
+	c.MDSizeMultiplier = rd.readInt() //serverVersion 110
+	c.OrdeTypes = rd.readString()
+	c.ValidExchange = rd.readString()
+	c.NextOptionDate = rd.readString()  //version 2
+	c.NextOptionType = rd.readString()  //version 2
+	c.NextOptionPartial = rd.readBool() //version 2
+	c.Notes = rd.readString()           //version 2
+	c.LongName = rd.readString()        //version 4
+	c.EVRule = rd.readString()          //version 6
+	c.EVMultiplier = rd.readInt()       //version 6
+	if n := int(rd.readInt()); n > 0 {  //version 5
+		c.SecIDList = make([]TagValue, n)
+		for i := 0; i < n; i++ {
+			c.SecIDList[i] = TagValue{Tag: rd.readString(), Value: rd.readString()}
+		}
+	}
+	c.AggGroup = rd.readInt()         //serverVersion 121
+	c.MarketRuleIDs = rd.readString() //serverVersion 126
+}
+
 func decodeContractDataEnd(m *Message, rd *msgReader) {
 	m.code = rd.readString()
 	rd.discard()
@@ -127,18 +182,15 @@ func decodeHeadTimeStamp(m *Message, rd *msgReader) {
 func decodeHistoricalData(m *Message, rd *msgReader) {
 	m.code = rd.readString()
 	m.id = rd.readString()
-	// h := &HistoricalData{}
-	// m.body = h
-	// h.StartDateStr = rd.readString()
-	// h.EndDateStr = rd.readString()
-	// h.ItemCount = rd.readInt()
-	rd.discard()
-	rd.discard()
-	bars := make([]BarData, int(rd.readInt()))
-	m.body = bars
-	// h.Bars = make([]BarData, int(h.ItemCount))
-	for i := 0; i < len(bars); i++ {
-		bars[i] = rd.readBar()
+	h := &HistoricalData{}
+	m.body = h
+	h.StartDateStr = rd.readString()
+	h.EndDateStr = rd.readString()
+	h.ItemCount = rd.readInt()
+	h.Bars = make([]BarData, int(h.ItemCount))
+	h.barTimes = make([]string, int(h.ItemCount))
+	for i := 0; i < len(h.Bars); i++ {
+		h.Bars[i], h.barTimes[i] = rd.readBar()
 	}
 }
 
@@ -176,3 +228,171 @@ func decodeHistoricalTick(m *Message, rd *msgReader) {
 	}
 
 }
+
+func decodeNewsProviders(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = "*" + inNEWSPROVIDERS
+	providers := make([]NewsProvider, int(rd.readInt()))
+	m.body = providers
+	for i := 0; i < len(providers); i++ {
+		providers[i].Code = rd.readString()
+		providers[i].Name = rd.readString()
+	}
+}
+
+func decodeHistoricalNews(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	n := &HistoricalNews{}
+	m.body = n
+	n.Time, _ = time.ParseInLocation(HistoricalNewsTimeLayout, rd.readString(), time.UTC)
+	n.ProviderCode = rd.readString()
+	n.ArticleID = rd.readString()
+	n.Headline = rd.readString()
+}
+
+func decodeHistoricalNewsEnd(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	m.body = &HistoricalNewsEnd{rd.readBool()}
+}
+
+func decodeNewsArticle(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	m.body = &NewsArticle{Type: rd.readInt(), Text: rd.readString()}
+}
+
+func decodeTickNews(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	t := &TickNews{}
+	m.body = t
+	t.Time = time.Unix(0, rd.readInt()*int64(time.Millisecond))
+	t.ProviderCode = rd.readString()
+	t.ArticleID = rd.readString()
+	t.Headline = rd.readString()
+	t.ExtraData = rd.readString()
+}
+
+func decodeNewsBulletins(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	rd.discard()
+	m.id = "*" + inNEWSBULLETINS
+	b := &NewsBulletin{}
+	m.body = b
+	b.MsgID = rd.readInt()
+	b.MsgType = rd.readInt()
+	b.Message = rd.readString()
+	b.OriginExch = rd.readString()
+}
+
+func decodeHistogramData(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	entries := make([]HistogramEntry, int(rd.readInt()))
+	m.body = entries
+	for i := 0; i < len(entries); i++ {
+		entries[i].Price = rd.readFloat()
+		entries[i].Size = rd.readInt()
+	}
+}
+
+func decodeMarketRule(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	r := &MarketRule{}
+	m.body = r
+	r.ID = rd.readInt()
+	m.id = "*" + inMARKETRULE + strconv.FormatInt(r.ID, 10)
+	r.Increments = make([]PriceIncrement, int(rd.readInt()))
+	for i := 0; i < len(r.Increments); i++ {
+		r.Increments[i].LowEdge = rd.readFloat()
+		r.Increments[i].Increment = rd.readFloat()
+	}
+}
+
+func decodeSmartComponents(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	components := make([]SmartComponent, int(rd.readInt()))
+	m.body = components
+	for i := 0; i < len(components); i++ {
+		components[i].BitNumber = rd.readInt()
+		components[i].Exchange = rd.readString()
+		components[i].ExchangeLetter = rd.readString()
+	}
+}
+
+func decodeTickReqParams(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	p := &TickReqParams{}
+	m.body = p
+	p.MinTick = rd.readFloat()
+	p.BBOExchange = rd.readString()
+	p.SnapshotPermissions = rd.readInt()
+}
+
+func decodeFamilyCodes(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = "*" + inFAMILYCODES
+	codes := make([]FamilyCode, int(rd.readInt()))
+	m.body = codes
+	for i := 0; i < len(codes); i++ {
+		codes[i].AccountID = rd.readString()
+		codes[i].FamilyCode = rd.readString()
+	}
+}
+
+func decodeSoftDollarTiers(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	tiers := make([]SoftDollarTier, int(rd.readInt()))
+	m.body = tiers
+	for i := 0; i < len(tiers); i++ {
+		tiers[i].Name = rd.readString()
+		tiers[i].Value = rd.readString()
+		tiers[i].DisplayName = rd.readString()
+	}
+}
+
+func decodeReceiveFA(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	rd.discard()
+	fa := &ReceiveFA{}
+	m.body = fa
+	fa.FADataType = rd.readInt()
+	m.id = "*" + inRECEIVEFA + strconv.FormatInt(fa.FADataType, 10)
+	fa.XML = rd.readString()
+}
+
+func decodeReplaceFAEnd(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	m.id = rd.readString()
+	m.body = rd.readString()
+}
+
+func decodeDisplayGroupList(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	rd.discard()
+	m.id = rd.readString()
+	m.body = rd.readString()
+}
+
+func decodeDisplayGroupUpdated(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	rd.discard()
+	m.id = rd.readString()
+	m.body = rd.readString()
+}
+
+func decodeDeltaNeutralValidation(m *Message, rd *msgReader) {
+	m.code = rd.readString()
+	rd.discard()
+	m.id = rd.readString()
+	d := &DeltaNeutralContract{}
+	m.body = d
+	d.ContractID = rd.readInt()
+	d.Delta = rd.readFloat()
+	d.Price = rd.readFloat()
+}
@@ -0,0 +1,255 @@
+package ibgo
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxBarDuration is the longest span IB serves in one historical bar request per bar size
+var MaxBarDuration = map[BarSize]time.Duration{
+	"1 secs":  time.Minute * 30,
+	"5 secs":  time.Hour,
+	"10 secs": time.Hour * 4,
+	"15 secs": time.Hour * 4,
+	"30 secs": time.Hour * 8,
+	"1 min":   time.Hour * 24,
+	"2 mins":  time.Hour * 24 * 2,
+	"3 mins":  time.Hour * 24 * 7,
+	"5 mins":  time.Hour * 24 * 7,
+	"10 mins": time.Hour * 24 * 7,
+	"15 mins": time.Hour * 24 * 7,
+	"20 mins": time.Hour * 24 * 7,
+	"30 mins": time.Hour * 24 * 30,
+	"1 hour":  time.Hour * 24 * 30,
+	"2 hours": time.Hour * 24 * 30,
+	"3 hours": time.Hour * 24 * 30,
+	"4 hours": time.Hour * 24 * 30,
+	"8 hours": time.Hour * 24 * 30,
+	"1 day":   time.Hour * 24 * 365,
+	"1 week":  time.Hour * 24 * 365 * 2,
+	"1 month": time.Hour * 24 * 365 * 2,
+}
+
+// DownloadRetries is how many times a chunk is retried after a pacing violation
+var DownloadRetries = 5
+
+// retryDelay is waited times the attempt before retrying a paced request
+var retryDelay = time.Second * 10
+
+func isPacingViolation(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "pacing violation")
+}
+
+func isNoData(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "returned no data")
+}
+
+// paced calls fetch until it succeeds or fails other than by a pacing violation, at most DownloadRetries times more
+func paced(fetch func() error) (err error) {
+	for i := 0; ; i++ {
+		err = fetch()
+		if isPacingViolation(err) && i < DownloadRetries {
+			time.Sleep(retryDelay * time.Duration(i+1))
+			continue
+		}
+		return
+	}
+}
+
+// DownloadBars fetches every bar in [start, end) by walking backwards in chunks IB accepts,
+// stopping at the head time stamp. Pacing violations are retried and overlaps are dropped
+func (ins *Instrument) DownloadBars(start time.Time, end time.Time, barSize BarSize, whatToShow WhatToShow, useRTH bool) (bars []BarData, err error) {
+	if _, ok := MaxBarDuration[barSize]; !ok {
+		return nil, fmt.Errorf("Unknown bar size %v", barSize)
+	}
+	if head, err := ins.HeadTimeStamp(whatToShow, useRTH); err == nil && head.After(start) {
+		start = head
+	}
+	return downloadBars(start, end, barSize, func(chunkEnd time.Time, duration Duration) ([]BarData, error) {
+		chunk, _, _, err := ins.HistoricalBar(FormatDateTime(chunkEnd), duration, barSize, whatToShow, useRTH, false)
+		return chunk, err
+	})
+}
+
+// downloadBars walks [start, end) backwards in chunks of barSize, fetching the bars of duration up to each chunk end
+func downloadBars(start time.Time, end time.Time, barSize BarSize, fetch func(chunkEnd time.Time, duration Duration) ([]BarData, error)) (bars []BarData, err error) {
+	maxDur := MaxBarDuration[barSize]
+	minDur := minDurationFor(barSize)
+	type timedBar struct {
+		t   time.Time
+		bar BarData
+	}
+	seen := make(map[int64]timedBar)
+	for chunkEnd := end; chunkEnd.After(start); {
+		chunkStart := chunkEnd.Add(-maxDur)
+		if chunkStart.Before(start) {
+			chunkStart = start
+		}
+		span := chunkEnd.Sub(chunkStart)
+		if span < minDur {
+			span = minDur
+		}
+		var chunk []BarData
+		err = paced(func() (err error) {
+			chunk, err = fetch(chunkEnd, DurationOf(span))
+			return
+		})
+		if isNoData(err) {
+			err = nil
+		} else if err != nil {
+			return nil, err
+		}
+		for _, bar := range chunk {
+			if !bar.Time.Before(start) && bar.Time.Before(end) {
+				seen[bar.Time.UnixNano()] = timedBar{bar.Time, bar}
+			}
+		}
+		chunkEnd = chunkStart
+	}
+	ordered := make([]timedBar, 0, len(seen))
+	for _, tb := range seen {
+		ordered = append(ordered, tb)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].t.Before(ordered[j].t) })
+	bars = make([]BarData, len(ordered))
+	for i, tb := range ordered {
+		bars[i] = tb.bar
+	}
+	return
+}
+
+// TicksPerPage is the number of ticks asked for per historical ticks request, IB serves at most 1000
+var TicksPerPage int64 = 1000
+
+// DownloadTicks walks [start, end) forward page by page and hands every tick to fn in order.
+// Pages are cut at whole seconds so that ticks repeated at page boundaries are dropped. Ticks sharing
+// a second come spread apart by HistoricalTicks, so a second is never split over two pages.
+// Session bits are carried across pages as if the range had come in one piece
+func (ins *Instrument) DownloadTicks(start time.Time, end time.Time, whatToShow WhatToShow, useRTH bool, fn func(Tick) error) error {
+	j := &pageJoin{ins: ins, fn: fn}
+	err := downloadTicks(start, end, func(from time.Time) ([]Tick, error) {
+		return ins.HistoricalTicks(FormatDateTime(from), "", TicksPerPage, whatToShow, useRTH)
+	}, j.add)
+	if err != nil {
+		return err
+	}
+	return j.flush()
+}
+
+// downloadTicks walks [start, end) forward, fetching the page of ticks from each whole second on
+func downloadTicks(start time.Time, end time.Time, fetch func(from time.Time) ([]Tick, error), fn func(Tick) error) error {
+	from := start
+	for from.Before(end) {
+		var page []Tick
+		err := paced(func() (err error) {
+			page, err = fetch(from)
+			return
+		})
+		if isNoData(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		last := page[len(page)-1].Time.Truncate(time.Second)
+		complete := int64(len(page)) < TicksPerPage
+		if !complete {
+			// the last second may continue on the next page, leave it for that page unless it is all we got
+			cut := len(page)
+			for cut > 0 && page[cut-1].Time.Truncate(time.Second).Equal(last) {
+				cut--
+			}
+			if cut > 0 {
+				page = page[:cut]
+			} else {
+				last = last.Add(time.Second)
+			}
+		}
+		if err = emitTicks(page, start, end, fn); err != nil {
+			return err
+		}
+		if complete {
+			return nil
+		}
+		if !last.After(from) {
+			last = from.Add(time.Second)
+		}
+		from = last
+	}
+	return nil
+}
+
+// emitTicks passes the ticks inside [start, end) to fn
+func emitTicks(ticks []Tick, start time.Time, end time.Time, fn func(Tick) error) error {
+	for _, t := range ticks {
+		if t.Time.Before(start) || !t.Time.Before(end) {
+			continue
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageJoin passes ticks on one behind, as a page cannot tell whether its first tick opens a session
+// or whether its last one closes it until the neighbouring page is seen
+type pageJoin struct {
+	ins     *Instrument
+	fn      func(Tick) error
+	held    Tick
+	holding bool
+	session Session
+	in      bool
+}
+
+func (j *pageJoin) add(t Tick) error {
+	s, in, _ := j.ins.SessionFor(t.Time)
+	if j.holding {
+		if in && (!j.in || s != j.session) {
+			t.SetSessionStart()
+		}
+		if j.in && (!in || s != j.session) {
+			j.held.SetSessionEnd()
+		}
+		if err := j.fn(j.held); err != nil {
+			return err
+		}
+	}
+	j.held, j.holding, j.session, j.in = t, true, s, in
+	return nil
+}
+
+func (j *pageJoin) flush() error {
+	if !j.holding {
+		return nil
+	}
+	j.holding = false
+	return j.fn(j.held)
+}
+
+// DownloadTicksTo writes the ticks of [start, end) to w as JSON lines
+func (ins *Instrument) DownloadTicksTo(w io.Writer, start time.Time, end time.Time, whatToShow WhatToShow, useRTH bool) error {
+	bw := bufio.NewWriter(w)
+	err := ins.DownloadTicks(start, end, whatToShow, useRTH, func(t Tick) error {
+		b, err := json.Marshal(&t)
+		if err != nil {
+			return err
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
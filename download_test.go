@@ -0,0 +1,187 @@
+package ibgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDownloadBarsChunks(t *testing.T) {
+	retryDelay = 0
+	defer func() { retryDelay = time.Second * 10 }()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 0, 30, 0, 0, time.UTC)
+	type call struct {
+		end      time.Time
+		duration Duration
+	}
+	// a 30 day chunk, then the half hour left widened to the hour IB takes at least with 1 hour bars
+	chunks := []call{{end, "30 D"}, {start.Add(time.Minute * 30), "3600 S"}}
+	paced := errors.New("Error162: Historical Market Data Service error message:API historical data query cancelled: pacing violation")
+	noData := errors.New("Error162: Historical Market Data Service error message:HMDS query returned no data")
+	failed := errors.New("Error321: Error validating request")
+	tests := []struct {
+		name  string
+		fail  map[int]error
+		calls []call
+		bars  int
+		err   error
+	}{
+		{"all", nil, chunks, 30*24 + 1, nil},
+		{"paced", map[int]error{0: paced, 1: paced}, []call{chunks[0], chunks[0], chunks[0], chunks[1]}, 30*24 + 1, nil},
+		{"paced out", map[int]error{0: paced, 1: paced, 2: paced, 3: paced, 4: paced, 5: paced},
+			[]call{chunks[0], chunks[0], chunks[0], chunks[0], chunks[0], chunks[0]}, 0, paced},
+		// the chunk overlap still brings the first hour
+		{"no data", map[int]error{1: noData}, chunks, 30*24 + 1, nil},
+		{"failed", map[int]error{1: failed}, chunks, 0, failed},
+	}
+	for _, tt := range tests {
+		calls := make([]call, 0)
+		bars, err := downloadBars(start, end, Bar1Hour, func(chunkEnd time.Time, duration Duration) ([]BarData, error) {
+			calls = append(calls, call{chunkEnd, duration})
+			if err := tt.fail[len(calls)-1]; err != nil {
+				return nil, err
+			}
+			length, err := duration.Length()
+			if err != nil {
+				return nil, err
+			}
+			// hourly bars reaching an hour before the duration asked for, so that chunks overlap
+			chunk := make([]BarData, 0)
+			for bt := chunkEnd.Add(-length - time.Hour).Truncate(time.Hour); bt.Before(chunkEnd); bt = bt.Add(time.Hour) {
+				if !bt.Before(chunkEnd.Add(-length - time.Hour)) {
+					chunk = append(chunk, BarData{Time: bt})
+				}
+			}
+			return chunk, nil
+		})
+		if err != tt.err {
+			t.Errorf("%v: error %v, want %v", tt.name, err, tt.err)
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(tt.calls) {
+			t.Errorf("%v: fetched %v, want %v", tt.name, calls, tt.calls)
+		}
+		if len(bars) != tt.bars {
+			t.Errorf("%v: %v bars, want %v", tt.name, len(bars), tt.bars)
+			continue
+		}
+		for i, bar := range bars {
+			if want := start.Add(time.Duration(i) * time.Hour); !bar.Time.Equal(want) {
+				t.Errorf("%v: bar %v at %v, want %v", tt.name, i, bar.Time, want)
+				break
+			}
+		}
+	}
+}
+
+// tickPages serves ticks as HistoricalTicks does, the first TicksPerPage of them from a whole second on
+func tickPages(ticks []Tick) func(from time.Time) ([]Tick, error) {
+	return func(from time.Time) ([]Tick, error) {
+		page := make([]Tick, 0)
+		for _, t := range ticks {
+			if !t.Time.Before(from) && int64(len(page)) < TicksPerPage {
+				page = append(page, t)
+			}
+		}
+		return page, nil
+	}
+}
+
+// secondTicks returns a tick at each of seconds past base, spread apart like HistoricalTicks does
+func secondTicks(base time.Time, seconds ...int) []Tick {
+	ticks := make([]Tick, len(seconds))
+	for i, s := range seconds {
+		ticks[i].Time = base.Add(time.Duration(s) * time.Second)
+	}
+	shiftTicks(ticks)
+	return ticks
+}
+
+func TestDownloadTicksPages(t *testing.T) {
+	defer func(n int64) { TicksPerPage = n }(TicksPerPage)
+	TicksPerPage = 3
+	base := time.Date(2024, 1, 9, 10, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return base.Add(time.Duration(s) * time.Second) }
+	ms := func(ticks []Tick) []int64 {
+		got := make([]int64, len(ticks))
+		for i, t := range ticks {
+			got[i] = int64(t.Time.Sub(base) / time.Millisecond)
+		}
+		return got
+	}
+	spread := secondTicks(base, 0, 0, 1, 2, 2, 2, 3, 4)
+	failed := errors.New("Error321: Error validating request")
+	tests := []struct {
+		name       string
+		ticks      []Tick
+		start, end int
+		fail       map[int]error
+		want       []int64
+		err        error
+	}{
+		// pages are cut before their last second, which opens the next page
+		{"spread", spread, 0, 10, nil, []int64{0, 1, 1000, 2000, 2001, 2002, 3000, 4000}, nil},
+		{"bounded", spread, 1, 3, nil, []int64{1000, 2000, 2001, 2002}, nil},
+		// a second holding more than a page keeps only the page
+		{"crowded", secondTicks(base, 0, 1, 1, 1, 1, 2), 0, 10, nil, []int64{0, 1000, 1001, 1002, 2000}, nil},
+		{"no data", spread, 0, 10, map[int]error{1: errors.New("Error162: HMDS query returned no data")}, []int64{0, 1}, nil},
+		{"failed", spread, 0, 10, map[int]error{1: failed}, []int64{0, 1}, failed},
+	}
+	for _, tt := range tests {
+		pages := tickPages(tt.ticks)
+		calls := 0
+		got := make([]Tick, 0)
+		err := downloadTicks(at(tt.start), at(tt.end), func(from time.Time) ([]Tick, error) {
+			calls++
+			if err := tt.fail[calls-1]; err != nil {
+				return nil, err
+			}
+			if from.Truncate(time.Second) != from {
+				t.Errorf("%v: page asked from %v, within a second", tt.name, from)
+			}
+			return pages(from)
+		}, func(t Tick) error {
+			got = append(got, t)
+			return nil
+		})
+		if err != tt.err {
+			t.Errorf("%v: error %v, want %v", tt.name, err, tt.err)
+		}
+		if fmt.Sprint(ms(got)) != fmt.Sprint(tt.want) {
+			t.Errorf("%v: ticks at %v ms, want %v", tt.name, ms(got), tt.want)
+		}
+	}
+}
+
+func TestDownloadTicksSessionsAcrossPages(t *testing.T) {
+	defer func(n int64) { TicksPerPage = n }(TicksPerPage)
+	TicksPerPage = 2
+	ins, loc := weekInstrument(t)
+	at := func(day, h, m, s int) time.Time { return time.Date(2024, 1, day, h, m, s, 0, loc) }
+	ticks := []Tick{
+		{Time: at(9, 15, 59, 58)}, {Time: at(9, 15, 59, 59)},
+		{Time: at(9, 16, 0, 1)}, {Time: at(10, 9, 30, 0)},
+		{Time: at(10, 9, 30, 1)},
+	}
+	want := []TickMask{0, SessionEnd, 0, SessionStart, 0}
+	got := make([]Tick, 0)
+	j := &pageJoin{ins: ins, fn: func(t Tick) error {
+		got = append(got, t)
+		return nil
+	}}
+	if err := downloadTicks(at(9, 15, 0, 0), at(10, 10, 0, 0), tickPages(ticks), j.add); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%v ticks, want %v", len(got), len(want))
+	}
+	for i := range want {
+		if mask := got[i].Mask & (SessionStart | SessionEnd); mask != want[i] || !got[i].Time.Equal(ticks[i].Time) {
+			t.Errorf("tick at %v with session bits %v, want %v at %v", got[i].Time, mask, want[i], ticks[i].Time)
+		}
+	}
+}
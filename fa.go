@@ -0,0 +1,223 @@
+package ibgo
+
+import (
+	"encoding/xml"
+)
+
+// FA data type
+const (
+	FAGroups int64 = iota + 1
+	FAProfiles
+	FAAliases
+)
+
+// faNode is an element of an FA document this package does not model
+type faNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   []byte     `xml:",innerxml"`
+}
+
+// faExtra keeps the attributes, such as IB's varName, and the elements of an FA document beyond the modelled
+// fields, so that a configuration read with ReqFA and written back with ReplaceFA loses nothing. Unknown
+// elements are written back after the known ones
+type faExtra struct {
+	Attrs []xml.Attr `xml:",any,attr"`
+	Nodes []faNode   `xml:",any"`
+}
+
+type FAGroup struct {
+	Name          string
+	Accounts      []string
+	DefaultMethod string
+	extra         faExtra
+	listExtra     faExtra
+}
+
+type faAccountList struct {
+	Accounts []string `xml:"String"`
+	faExtra
+}
+
+type faGroupXML struct {
+	Name          string        `xml:"name"`
+	Accounts      faAccountList `xml:"ListOfAccts"`
+	DefaultMethod string        `xml:"defaultMethod"`
+	faExtra
+}
+
+func (g FAGroup) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(faGroupXML{g.Name, faAccountList{g.Accounts, g.listExtra}, g.DefaultMethod, g.extra}, start)
+}
+
+func (g *FAGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	x := faGroupXML{}
+	if err := d.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	*g = FAGroup{x.Name, x.Accounts.Accounts, x.DefaultMethod, x.faExtra, x.Accounts.faExtra}
+	return nil
+}
+
+type faGroupList struct {
+	XMLName xml.Name  `xml:"ListOfGroups"`
+	Groups  []FAGroup `xml:"Group"`
+}
+
+type FAAllocation struct {
+	Account string
+	Amount  float64
+	extra   faExtra
+}
+
+type faAllocationXML struct {
+	Account string  `xml:"acct"`
+	Amount  float64 `xml:"amount"`
+	faExtra
+}
+
+func (a FAAllocation) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(faAllocationXML{a.Account, a.Amount, a.extra}, start)
+}
+
+func (a *FAAllocation) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	x := faAllocationXML{}
+	if err := d.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	*a = FAAllocation{x.Account, x.Amount, x.faExtra}
+	return nil
+}
+
+type FAProfile struct {
+	Name        string
+	Type        int64
+	Allocations []FAAllocation
+	extra       faExtra
+	listExtra   faExtra
+}
+
+type faAllocationList struct {
+	Allocations []FAAllocation `xml:"Allocation"`
+	faExtra
+}
+
+type faProfileXML struct {
+	Name        string           `xml:"name"`
+	Type        int64            `xml:"type"`
+	Allocations faAllocationList `xml:"ListOfAllocations"`
+	faExtra
+}
+
+func (p FAProfile) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(faProfileXML{p.Name, p.Type, faAllocationList{p.Allocations, p.listExtra}, p.extra}, start)
+}
+
+func (p *FAProfile) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	x := faProfileXML{}
+	if err := d.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	*p = FAProfile{x.Name, x.Type, x.Allocations.Allocations, x.faExtra, x.Allocations.faExtra}
+	return nil
+}
+
+type faProfileList struct {
+	XMLName  xml.Name    `xml:"ListOfAllocationProfiles"`
+	Profiles []FAProfile `xml:"AllocationProfile"`
+}
+
+type FAAlias struct {
+	Account string
+	Alias   string
+	extra   faExtra
+}
+
+type faAliasXML struct {
+	Account string `xml:"account"`
+	Alias   string `xml:"alias"`
+	faExtra
+}
+
+func (a FAAlias) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(faAliasXML{a.Account, a.Alias, a.extra}, start)
+}
+
+func (a *FAAlias) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	x := faAliasXML{}
+	if err := d.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	*a = FAAlias{x.Account, x.Alias, x.faExtra}
+	return nil
+}
+
+type faAliasList struct {
+	XMLName xml.Name  `xml:"ListOfAccountAliases"`
+	Aliases []FAAlias `xml:"AccountAlias"`
+}
+
+func marshalFA(v interface{}) (string, error) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b), nil
+}
+
+func (c *IBClient) ReqFAGroups() (groups []FAGroup, err error) {
+	str, err := c.ReqFA(FAGroups)
+	if err != nil {
+		return
+	}
+	l := faGroupList{}
+	err = xml.Unmarshal([]byte(str), &l)
+	groups = l.Groups
+	return
+}
+
+func (c *IBClient) ReplaceFAGroups(groups []FAGroup) error {
+	str, err := marshalFA(faGroupList{Groups: groups})
+	if err != nil {
+		return err
+	}
+	return c.ReplaceFA(FAGroups, str)
+}
+
+func (c *IBClient) ReqFAProfiles() (profiles []FAProfile, err error) {
+	str, err := c.ReqFA(FAProfiles)
+	if err != nil {
+		return
+	}
+	l := faProfileList{}
+	err = xml.Unmarshal([]byte(str), &l)
+	profiles = l.Profiles
+	return
+}
+
+func (c *IBClient) ReplaceFAProfiles(profiles []FAProfile) error {
+	str, err := marshalFA(faProfileList{Profiles: profiles})
+	if err != nil {
+		return err
+	}
+	return c.ReplaceFA(FAProfiles, str)
+}
+
+func (c *IBClient) ReqFAAliases() (aliases []FAAlias, err error) {
+	str, err := c.ReqFA(FAAliases)
+	if err != nil {
+		return
+	}
+	l := faAliasList{}
+	err = xml.Unmarshal([]byte(str), &l)
+	aliases = l.Aliases
+	return
+}
+
+func (c *IBClient) ReplaceFAAliases(aliases []FAAlias) error {
+	str, err := marshalFA(faAliasList{Aliases: aliases})
+	if err != nil {
+		return err
+	}
+	return c.ReplaceFA(FAAliases, str)
+}
@@ -0,0 +1,141 @@
+package ibgo
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// FA documents as TWS sends them, with varName attributes and elements this package does not model
+const (
+	faGroupsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListOfGroups>
+  <Group>
+    <name>Equal_Quantity</name>
+    <ListOfAccts varName="list">
+      <String>DU119915</String>
+      <String>DU119916</String>
+    </ListOfAccts>
+    <defaultMethod>EqualQuantity</defaultMethod>
+  </Group>
+  <Group>
+    <name>Pct_Change</name>
+    <ListOfAccts varName="list">
+      <String>DU119915</String>
+    </ListOfAccts>
+    <defaultMethod>PctChange</defaultMethod>
+    <defaultSize>100</defaultSize>
+  </Group>
+</ListOfGroups>`
+	faProfilesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListOfAllocationProfiles>
+  <AllocationProfile>
+    <name>Percent_60_40</name>
+    <type>1</type>
+    <ListOfAllocations varName="listOfAllocations">
+      <Allocation>
+        <acct>DU119915</acct>
+        <amount>60.0</amount>
+        <posEff>O</posEff>
+      </Allocation>
+      <Allocation>
+        <acct>DU119916</acct>
+        <amount>40.0</amount>
+        <posEff>O</posEff>
+      </Allocation>
+    </ListOfAllocations>
+  </AllocationProfile>
+</ListOfAllocationProfiles>`
+	faAliasesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListOfAccountAliases>
+  <AccountAlias varName="alias">
+    <account>DU119915</account>
+    <alias>Main</alias>
+  </AccountAlias>
+</ListOfAccountAliases>`
+)
+
+// faTree flattens an XML document into its elements, attributes and non blank text, reading numbers as numbers
+func faTree(t *testing.T, doc string) []string {
+	t.Helper()
+	d := xml.NewDecoder(strings.NewReader(doc))
+	tree := make([]string, 0)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return tree
+		} else if err != nil {
+			t.Fatalf("%v in %s", err, doc)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			tree = append(tree, "<"+tok.Name.Local)
+			for _, a := range tok.Attr {
+				tree = append(tree, "@"+a.Name.Local+"="+a.Value)
+			}
+		case xml.EndElement:
+			tree = append(tree, ">"+tok.Name.Local)
+		case xml.CharData:
+			text := strings.TrimSpace(string(tok))
+			if f, err := strconv.ParseFloat(text, 64); err == nil {
+				text = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+			if text != "" {
+				tree = append(tree, text)
+			}
+		}
+	}
+}
+
+func TestFARoundTrip(t *testing.T) {
+	tests := []struct {
+		doc  string
+		list interface{}
+	}{
+		{faGroupsXML, &faGroupList{}},
+		{faProfilesXML, &faProfileList{}},
+		{faAliasesXML, &faAliasList{}},
+	}
+	for _, tt := range tests {
+		if err := xml.Unmarshal([]byte(tt.doc), tt.list); err != nil {
+			t.Fatal(err)
+		}
+		out, err := marshalFA(tt.list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := faTree(t, out), faTree(t, tt.doc); !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip changed the document\n got %v\nwant %v", got, want)
+		}
+	}
+}
+
+func TestFAFields(t *testing.T) {
+	groups := faGroupList{}
+	if err := xml.Unmarshal([]byte(faGroupsXML), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if g := groups.Groups[0]; g.Name != "Equal_Quantity" || !reflect.DeepEqual(g.Accounts, []string{"DU119915", "DU119916"}) || g.DefaultMethod != "EqualQuantity" {
+		t.Errorf("group %+v", g)
+	}
+	profiles := faProfileList{}
+	if err := xml.Unmarshal([]byte(faProfilesXML), &profiles); err != nil {
+		t.Fatal(err)
+	}
+	if p := profiles.Profiles[0]; p.Name != "Percent_60_40" || p.Type != 1 || len(p.Allocations) != 2 ||
+		p.Allocations[1].Account != "DU119916" || p.Allocations[1].Amount != 40 {
+		t.Errorf("profile %+v", p)
+	}
+	// groups built from scratch carry no extra content
+	out, err := marshalFA(faGroupList{Groups: []FAGroup{{Name: "G", Accounts: []string{"A"}, DefaultMethod: "NetLiq"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<ListOfGroups><Group><name>G</name><ListOfAccts><String>A</String></ListOfAccts><defaultMethod>NetLiq</defaultMethod></Group></ListOfGroups>"
+	if got := strings.Join(faTree(t, out), ""); got != strings.Join(faTree(t, want), "") {
+		t.Errorf("new group written as %s", out)
+	}
+}
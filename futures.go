@@ -0,0 +1,226 @@
+package ibgo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FutureChain returns every expiry of symbol on exchange quoted in currency, expired ones included, ordered by expiry
+func (c *IBClient) FutureChain(symbol string, exchange string, currency string) (chain []*Instrument, err error) {
+	if to, ok := CommonFutureSymbolMap[symbol]; ok {
+		symbol = to
+	}
+	condatas, err := c.ReqContractDetails(Contract{Symbol: symbol, SecType: "FUT", Currency: currency, Exchange: exchange, IncludeExpired: true})
+	if err != nil {
+		return
+	}
+	condatas = filterIgnored(condatas)
+	chain = make([]*Instrument, len(condatas))
+	for i, condata := range condatas {
+		condata.IncludeExpired = true
+		chain[i] = newInstrument(condata, c)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("No future found for %v", symbol)
+	}
+	sort.Slice(chain, func(i, j int) bool {
+		return chain[i].contract.LastTradeDateOrContractMonth < chain[j].contract.LastTradeDateOrContractMonth
+	})
+	return
+}
+
+// Expiry returns the last trade date of the instrument in its own time zone
+func (ins *Instrument) Expiry() (time.Time, error) {
+	loc, err := time.LoadLocation(ins.Detail.TimeZoneID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation("20060102", ins.contract.LastTradeDateOrContractMonth, loc)
+}
+
+type FutureSeries struct {
+	Instrument *Instrument
+	Bars       []BarData
+}
+
+// LoadFutureSeries downloads the bars of each contract of chain up to its expiry
+func LoadFutureSeries(chain []*Instrument, durationStr Duration, barSize BarSize, whatToShow WhatToShow, useRTH bool) (series []FutureSeries, err error) {
+	series = make([]FutureSeries, len(chain))
+	for i, ins := range chain {
+		expiry, err := ins.Expiry()
+		if err != nil {
+			return nil, err
+		}
+		end := ""
+		if expiry.Before(time.Now()) {
+			end = FormatDateTime(expiry.Add(time.Hour*24 - time.Second))
+		}
+		bars, _, _, err := ins.HistoricalBar(end, durationStr, barSize, whatToShow, useRTH, false)
+		if err != nil {
+			return nil, err
+		}
+		series[i] = FutureSeries{ins, bars}
+	}
+	return
+}
+
+// RollRule decides when a position moves from front to next
+type RollRule interface {
+	RollTime(front *FutureSeries, next *FutureSeries) (time.Time, error)
+}
+
+// RollDaysBeforeExpiry rolls a fixed number of calendar days before the front expiry
+type RollDaysBeforeExpiry struct {
+	Days int
+}
+
+func (r RollDaysBeforeExpiry) RollTime(front *FutureSeries, next *FutureSeries) (time.Time, error) {
+	expiry, err := front.Instrument.Expiry()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expiry.AddDate(0, 0, -r.Days), nil
+}
+
+// RollByVolume rolls on the bar following the first one where next trades more than front
+type RollByVolume struct{}
+
+func (r RollByVolume) RollTime(front *FutureSeries, next *FutureSeries) (time.Time, error) {
+	return rollOnCross(front, func(bar BarData) (float64, float64, bool) {
+		nb, ok := next.barAt(bar.Time)
+		return float64(bar.Volume), float64(nb.Volume), ok
+	})
+}
+
+// RollByOpenInterest rolls on the bar following the first one where next holds more open interest than front.
+// IB offers no historical open interest so it has to be supplied
+type RollByOpenInterest struct {
+	OpenInterest func(ins *Instrument, t time.Time) (int64, bool)
+}
+
+func (r RollByOpenInterest) RollTime(front *FutureSeries, next *FutureSeries) (time.Time, error) {
+	return rollOnCross(front, func(bar BarData) (float64, float64, bool) {
+		f, ok := r.OpenInterest(front.Instrument, bar.Time)
+		if !ok {
+			return 0, 0, false
+		}
+		n, ok := r.OpenInterest(next.Instrument, bar.Time)
+		return float64(f), float64(n), ok
+	})
+}
+
+// rollOnCross falls back to the front expiry when next never overtakes front
+func rollOnCross(front *FutureSeries, values func(bar BarData) (float64, float64, bool)) (time.Time, error) {
+	for i, bar := range front.Bars {
+		if f, n, ok := values(bar); ok && n > f && i+1 < len(front.Bars) {
+			return front.Bars[i+1].Time, nil
+		}
+	}
+	return front.Instrument.Expiry()
+}
+
+func (s *FutureSeries) barAt(t time.Time) (BarData, bool) {
+	i := sort.Search(len(s.Bars), func(i int) bool { return !s.Bars[i].Time.Before(t) })
+	if i < len(s.Bars) && s.Bars[i].Time.Equal(t) {
+		return s.Bars[i], true
+	}
+	return BarData{}, false
+}
+
+// lastBefore returns the last bar starting before t
+func (s *FutureSeries) lastBefore(t time.Time) (BarData, bool) {
+	for i := len(s.Bars) - 1; i >= 0; i-- {
+		if s.Bars[i].Time.Before(t) {
+			return s.Bars[i], true
+		}
+	}
+	return BarData{}, false
+}
+
+// FrontMonth returns the contract to hold at t under rule
+func FrontMonth(series []FutureSeries, rule RollRule, t time.Time) (*Instrument, error) {
+	for i := 0; i+1 < len(series); i++ {
+		roll, err := rule.RollTime(&series[i], &series[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if t.Before(roll) {
+			return series[i].Instrument, nil
+		}
+	}
+	if len(series) == 0 {
+		return nil, nil
+	}
+	return series[len(series)-1].Instrument, nil
+}
+
+// Continuous series adjustment
+const (
+	AdjustNone = iota
+	AdjustBack
+	AdjustRatio
+)
+
+// StitchFutures joins per expiry bars into one continuous series, rolling under rule.
+// AdjustBack shifts older prices by the roll gap, AdjustRatio scales them by the roll ratio. series is left as given
+func StitchFutures(series []FutureSeries, rule RollRule, adjust int) (bars []BarData, err error) {
+	if len(series) == 0 {
+		return
+	}
+	series = append([]FutureSeries(nil), series...)
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Instrument.contract.LastTradeDateOrContractMonth < series[j].Instrument.contract.LastTradeDateOrContractMonth
+	})
+	rolls := make([]time.Time, len(series)-1)
+	for i := range rolls {
+		if rolls[i], err = rule.RollTime(&series[i], &series[i+1]); err != nil {
+			return nil, err
+		}
+		if i > 0 && rolls[i].Before(rolls[i-1]) {
+			rolls[i] = rolls[i-1]
+		}
+	}
+	segments := make([][]BarData, len(series))
+	for i, s := range series {
+		segments[i] = make([]BarData, 0)
+		for _, bar := range s.Bars {
+			t := bar.Time
+			if i > 0 && t.Before(rolls[i-1]) || i < len(rolls) && !t.Before(rolls[i]) {
+				continue
+			}
+			segments[i] = append(segments[i], bar)
+		}
+	}
+	offset, factor := 0.0, 1.0
+	for i := len(segments) - 1; i >= 0; i-- {
+		if i < len(rolls) {
+			front, ok1 := series[i].lastBefore(rolls[i])
+			next, ok2 := series[i+1].lastBefore(rolls[i])
+			if !ok1 || !ok2 {
+				if adjust != AdjustNone {
+					return nil, fmt.Errorf("No overlapping bars to adjust the roll into %v", series[i+1].Instrument.contract.LocalSymbol)
+				}
+			} else {
+				offset += next.Close - front.Close
+				if front.Close != 0 {
+					factor *= next.Close / front.Close
+				}
+			}
+		}
+		for j := range segments[i] {
+			bar := &segments[i][j]
+			switch adjust {
+			case AdjustBack:
+				bar.Open, bar.High, bar.Low, bar.Close = bar.Open+offset, bar.High+offset, bar.Low+offset, bar.Close+offset
+			case AdjustRatio:
+				bar.Open, bar.High, bar.Low, bar.Close = bar.Open*factor, bar.High*factor, bar.Low*factor, bar.Close*factor
+			}
+		}
+	}
+	bars = make([]BarData, 0)
+	for _, seg := range segments {
+		bars = append(bars, seg...)
+	}
+	return
+}
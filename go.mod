@@ -2,4 +2,8 @@ module github.com/jinspiration/ibgo
 
 go 1.15
 
-require github.com/influxdata/influxdb-client-go/v2 v2.2.1
+require (
+	github.com/influxdata/influxdb-client-go/v2 v2.2.1
+	github.com/xitongsys/parquet-go v1.6.0
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
+)
@@ -0,0 +1,132 @@
+package indicators
+
+import (
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// SMA is the simple moving average of the last n values
+type SMA struct {
+	n   int
+	w   window
+	sum float64
+}
+
+func NewSMA(n int) *SMA {
+	return &SMA{n: n, w: newWindow(n)}
+}
+
+func (s *SMA) Update(v float64) (float64, bool) {
+	if old, evicted := s.w.push(v); evicted {
+		s.sum -= old
+	}
+	s.sum += v
+	if !s.w.full {
+		return 0, false
+	}
+	return s.sum / float64(len(s.w.vals)), true
+}
+
+func (s *SMA) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	return s.Update(bar.Close)
+}
+
+func (s *SMA) UpdateTick(t ibgo.Tick) (float64, bool) {
+	if p := price(t); p != 0 {
+		return s.Update(p)
+	}
+	return 0, false
+}
+
+// EMA is the exponential moving average with smoothing 2/(n+1), seeded with the SMA of the first n values
+type EMA struct {
+	alpha float64
+	seed  *SMA
+	value float64
+	ready bool
+}
+
+func NewEMA(n int) *EMA {
+	return &EMA{alpha: 2 / float64(n+1), seed: NewSMA(n)}
+}
+
+func (e *EMA) Update(v float64) (float64, bool) {
+	if !e.ready {
+		e.value, e.ready = e.seed.Update(v)
+		return e.value, e.ready
+	}
+	e.value += e.alpha * (v - e.value)
+	return e.value, true
+}
+
+func (e *EMA) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	return e.Update(bar.Close)
+}
+
+func (e *EMA) UpdateTick(t ibgo.Tick) (float64, bool) {
+	if p := price(t); p != 0 {
+		return e.Update(p)
+	}
+	return 0, false
+}
+
+// VWAP is the volume weighted average price since the start of the trading session, or since the
+// start of the data without an instrument. Sessions come from the instrument's calendar on every day;
+// when IB published no hours for it the average starts over at midnight in its time zone.
+// Bars count at their WAP, or their typical price when IB sent none
+type VWAP struct {
+	ins     *ibgo.Instrument
+	loc     *time.Location
+	session ibgo.Session
+	pv      float64
+	volume  float64
+}
+
+func NewVWAP(ins *ibgo.Instrument) *VWAP {
+	vw := &VWAP{ins: ins, loc: time.UTC}
+	if ins != nil {
+		if loc, err := time.LoadLocation(ins.Detail.TimeZoneID); err == nil {
+			vw.loc = loc
+		}
+	}
+	return vw
+}
+
+// Reset starts over
+func (vw *VWAP) Reset() {
+	vw.pv, vw.volume = 0, 0
+}
+
+func (vw *VWAP) add(t time.Time, p float64, size float64) (float64, bool) {
+	if vw.ins != nil {
+		session, ok, err := vw.ins.SessionFor(t)
+		if err == ibgo.ErrNoSession {
+			y, m, d := t.In(vw.loc).Date()
+			session.Start, ok = time.Date(y, m, d, 0, 0, 0, 0, vw.loc), true
+		}
+		if ok && !session.Start.Equal(vw.session.Start) {
+			vw.session = session
+			vw.Reset()
+		}
+	}
+	vw.pv += p * size
+	vw.volume += size
+	if vw.volume == 0 {
+		return 0, false
+	}
+	return vw.pv / vw.volume, true
+}
+
+func (vw *VWAP) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	p := bar.WAP
+	if p == 0 {
+		p = (bar.High + bar.Low + bar.Close) / 3
+	}
+	return vw.add(bar.Time, p, float64(bar.Volume))
+}
+
+// UpdateTick counts trades only, quotes carry no volume
+func (vw *VWAP) UpdateTick(t ibgo.Tick) (float64, bool) {
+	return vw.add(t.Time, t.Last, float64(t.Size))
+}
@@ -0,0 +1,145 @@
+// Package indicators computes technical indicators incrementally, one bar, tick or value at a time,
+// so that live streams never recompute history, and over whole series in batch
+package indicators
+
+import (
+	"math"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// Indicator is updated with one value at a time and reports whether it has seen enough to be valid
+type Indicator interface {
+	Update(v float64) (float64, bool)
+}
+
+// BarIndicator is updated with one bar at a time
+type BarIndicator interface {
+	UpdateBar(bar ibgo.BarData) (float64, bool)
+}
+
+// TickIndicator is updated with one tick at a time
+type TickIndicator interface {
+	UpdateTick(t ibgo.Tick) (float64, bool)
+}
+
+// Multi is implemented by indicators with more than one output, see MACD and Bollinger
+type Multi interface {
+	Components() []float64
+}
+
+// Point is a valid indicator value. Extra holds the Components of a Multi
+type Point struct {
+	Time  time.Time
+	Value float64
+	Extra []float64
+}
+
+func newPoint(t time.Time, v float64, ind interface{}) Point {
+	p := Point{Time: t, Value: v}
+	if m, ok := ind.(Multi); ok {
+		p.Extra = m.Components()
+	}
+	return p
+}
+
+// StreamBars updates ind with every bar and sends its valid values. The output closes with bars
+func StreamBars(bars <-chan ibgo.BarData, ind BarIndicator) <-chan Point {
+	out := make(chan Point)
+	go func() {
+		defer close(out)
+		for bar := range bars {
+			if v, ok := ind.UpdateBar(bar); ok {
+				out <- newPoint(bar.Time, v, ind)
+			}
+		}
+	}()
+	return out
+}
+
+// StreamTicks updates ind with every tick and sends its valid values. The output closes with ticks
+func StreamTicks(ticks <-chan ibgo.Tick, ind TickIndicator) <-chan Point {
+	out := make(chan Point)
+	go func() {
+		defer close(out)
+		for t := range ticks {
+			if v, ok := ind.UpdateTick(t); ok {
+				out <- newPoint(t.Time, v, ind)
+			}
+		}
+	}()
+	return out
+}
+
+// Batch returns the value of ind after each bar, NaN while it is not yet valid
+func Batch(bars []ibgo.BarData, ind BarIndicator) []float64 {
+	values := make([]float64, len(bars))
+	for i, bar := range bars {
+		values[i] = valid(ind.UpdateBar(bar))
+	}
+	return values
+}
+
+// BatchTicks returns the value of ind after each tick, NaN while it is not yet valid
+func BatchTicks(ticks []ibgo.Tick, ind TickIndicator) []float64 {
+	values := make([]float64, len(ticks))
+	for i, t := range ticks {
+		values[i] = valid(ind.UpdateTick(t))
+	}
+	return values
+}
+
+// BatchValues returns the value of ind after each of vs, NaN while it is not yet valid
+func BatchValues(vs []float64, ind Indicator) []float64 {
+	values := make([]float64, len(vs))
+	for i, v := range vs {
+		values[i] = valid(ind.Update(v))
+	}
+	return values
+}
+
+func valid(v float64, ok bool) float64 {
+	if !ok {
+		return math.NaN()
+	}
+	return v
+}
+
+// price is the trade price of t, or the quote midpoint for bid/ask and midpoint ticks
+func price(t ibgo.Tick) float64 {
+	switch {
+	case t.Last != 0:
+		return t.Last
+	case t.Midpoint != 0:
+		return t.Midpoint
+	case t.Bid != 0 && t.Ask != 0:
+		return (t.Bid + t.Ask) / 2
+	}
+	return 0
+}
+
+// window keeps the last n values
+type window struct {
+	vals []float64
+	next int
+	full bool
+}
+
+func newWindow(n int) window {
+	if n < 1 {
+		n = 1
+	}
+	return window{vals: make([]float64, n)}
+}
+
+// push adds v and returns the value it pushed out, if any
+func (w *window) push(v float64) (old float64, evicted bool) {
+	old, evicted = w.vals[w.next], w.full
+	w.vals[w.next] = v
+	w.next++
+	if w.next == len(w.vals) {
+		w.next, w.full = 0, true
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// closes is the RSI worked example from StockCharts, reference values below come from a plain
+// recomputation of each definition over the whole window at every step
+var closes = []float64{44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64}
+
+const tolerance = 1e-9
+
+// checkValues compares got with want from index first on and requires NaN before it
+func checkValues(t *testing.T, name string, got []float64, first int, want []float64) {
+	t.Helper()
+	for i := 0; i < first; i++ {
+		if !math.IsNaN(got[i]) {
+			t.Errorf("%v[%v] = %v before it is valid", name, i, got[i])
+		}
+	}
+	for i, w := range want {
+		if math.Abs(got[first+i]-w) > tolerance {
+			t.Errorf("%v[%v] = %v, want %v", name, first+i, got[first+i], w)
+		}
+	}
+}
+
+func TestSingleValued(t *testing.T) {
+	tests := []struct {
+		name  string
+		ind   Indicator
+		first int
+		want  []float64
+	}{
+		{"SMA(5)", NewSMA(5), 4, []float64{44.104, 44.202, 44.404, 44.658}},
+		{"SMA(1)", NewSMA(1), 0, closes[:3]},
+		{"EMA(5)", NewEMA(5), 4, []float64{44.104, 44.346, 44.59733333333333, 44.87155555555555}},
+		{"RSI(14)", NewRSI(14), 14, []float64{70.46413502109705, 66.24961855355505, 66.48094183471265,
+			69.34685316290866, 66.29471265892624, 57.91502067008556}},
+	}
+	for _, tt := range tests {
+		checkValues(t, tt.name, BatchValues(closes, tt.ind), tt.first, tt.want)
+	}
+}
+
+func TestEMAAfterSeed(t *testing.T) {
+	got := BatchValues(closes, NewEMA(5))
+	if w := 45.99605361941506; math.Abs(got[len(got)-1]-w) > tolerance {
+		t.Errorf("EMA(5) ends at %v, want %v", got[len(got)-1], w)
+	}
+}
+
+func TestRSIWithoutLosses(t *testing.T) {
+	got := BatchValues([]float64{1, 2, 3, 4}, NewRSI(2))
+	checkValues(t, "RSI(2)", got, 2, []float64{100, 100})
+}
+
+func TestMultiWarmUp(t *testing.T) {
+	checkValues(t, "MACD(3, 6, 4)", BatchValues(closes[:8], NewMACD(3, 6, 4)), 8, nil)
+	checkValues(t, "Bollinger(5, 2)", BatchValues(closes[:4], NewBollinger(5, 2)), 4, nil)
+}
+
+func TestMulti(t *testing.T) {
+	tests := []struct {
+		name string
+		ind  interface {
+			Indicator
+			Multi
+		}
+		index int
+		want  []float64
+	}{
+		{"MACD(3, 6, 4)", NewMACD(3, 6, 4), 8, []float64{0.41375744047618923, 0.33284040178571495, 0.08091703869047429}},
+		{"MACD(3, 6, 4)", NewMACD(3, 6, 4), 9, []float64{0.4259093324829948, 0.3700679740646269, 0.055841358418367903}},
+		{"MACD(3, 6, 4)", NewMACD(3, 6, 4), 19, []float64{-0.06354852124444932, 0.0417042779648594, -0.10525279920890252}},
+		{"Bollinger(5, 2)", NewBollinger(5, 2), 4, []float64{44.104, 44.63550352773994, 43.572496472260056}},
+		{"Bollinger(5, 2)", NewBollinger(5, 2), 19, []float64{46.06, 46.573030213535226, 45.54696978646478}},
+	}
+	for _, tt := range tests {
+		var v float64
+		var ok bool
+		for i := 0; i <= tt.index; i++ {
+			v, ok = tt.ind.Update(closes[i])
+		}
+		got := append([]float64{v}, tt.ind.Components()...)
+		if !ok {
+			t.Errorf("%v not valid at %v", tt.name, tt.index)
+		}
+		for k := range tt.want {
+			if math.Abs(got[k]-tt.want[k]) > tolerance {
+				t.Errorf("%v at %v: output %v = %v, want %v", tt.name, tt.index, k, got[k], tt.want[k])
+			}
+		}
+	}
+}
+
+func TestATR(t *testing.T) {
+	high := []float64{48.70, 48.72, 48.90, 48.87, 48.82, 49.05, 49.20, 49.35, 49.92, 50.19}
+	low := []float64{47.79, 48.14, 48.39, 48.37, 48.24, 48.64, 48.94, 48.86, 49.50, 49.87}
+	close := []float64{48.16, 48.61, 48.75, 48.63, 48.74, 49.03, 49.07, 49.32, 49.91, 50.13}
+	bars := make([]ibgo.BarData, len(high))
+	for i := range bars {
+		bars[i] = ibgo.BarData{High: high[i], Low: low[i], Close: close[i]}
+	}
+	checkValues(t, "ATR(5)", Batch(bars, NewATR(5)), 4, []float64{0.616, 0.5748, 0.51184, 0.507472, 0.5259776, 0.48478208})
+}
+
+func TestVWAPSessions(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(day, h int) time.Time { return time.Date(2024, 1, day, h, 0, 0, 0, loc) }
+	withHours := &ibgo.Instrument{}
+	withHours.Detail.TimeZoneID = "America/New_York"
+	for day := 8; day <= 12; day++ {
+		withHours.Detail.TradingHours = append(withHours.Detail.TradingHours, ibgo.Session{Start: at(day, 9), End: at(day, 16)})
+	}
+	withoutHours := &ibgo.Instrument{}
+	withoutHours.Detail.TimeZoneID = "America/New_York"
+	bars := []ibgo.BarData{
+		{Time: at(2, 10), WAP: 10, Volume: 1},
+		{Time: at(2, 11), WAP: 20, Volume: 3},
+		{Time: at(2, 20), WAP: 30, Volume: 4},
+		{Time: at(3, 10), WAP: 40, Volume: 1},
+	}
+	tests := []struct {
+		name string
+		ins  *ibgo.Instrument
+		want []float64
+	}{
+		// hours published for the week of the 8th carry over to the 2nd, the evening bar joins its session
+		{"weekly hours", withHours, []float64{10, 17.5, 23.75, 40}},
+		{"no hours", withoutHours, []float64{10, 17.5, 23.75, 40}},
+		{"no instrument", nil, []float64{10, 17.5, 23.75, 25.555555555555557}},
+	}
+	for _, tt := range tests {
+		checkValues(t, "VWAP "+tt.name, Batch(bars, NewVWAP(tt.ins)), 0, tt.want)
+	}
+	got := Batch([]ibgo.BarData{{Time: at(2, 23), WAP: 10, Volume: 1}, {Time: time.Date(2024, 1, 3, 0, 30, 0, 0, loc), WAP: 20, Volume: 1}}, NewVWAP(withoutHours))
+	checkValues(t, "VWAP over midnight", got, 0, []float64{10, 20})
+}
@@ -0,0 +1,108 @@
+package indicators
+
+import (
+	"github.com/jinspiration/ibgo"
+)
+
+// RSI is Wilder's relative strength index over n changes
+type RSI struct {
+	n      int
+	prev   float64
+	count  int
+	gain   float64
+	loss   float64
+	primed bool
+}
+
+func NewRSI(n int) *RSI {
+	return &RSI{n: n}
+}
+
+func (r *RSI) Update(v float64) (float64, bool) {
+	r.count++
+	if r.count == 1 {
+		r.prev = v
+		return 0, false
+	}
+	change := v - r.prev
+	r.prev = v
+	gain, loss := 0.0, 0.0
+	if change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+	n := float64(r.n)
+	if !r.primed {
+		r.gain += gain
+		r.loss += loss
+		if r.count <= r.n {
+			return 0, false
+		}
+		r.gain, r.loss, r.primed = r.gain/n, r.loss/n, true
+	} else {
+		r.gain = (r.gain*(n-1) + gain) / n
+		r.loss = (r.loss*(n-1) + loss) / n
+	}
+	if r.loss == 0 {
+		return 100, true
+	}
+	return 100 - 100/(1+r.gain/r.loss), true
+}
+
+func (r *RSI) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	return r.Update(bar.Close)
+}
+
+func (r *RSI) UpdateTick(t ibgo.Tick) (float64, bool) {
+	if p := price(t); p != 0 {
+		return r.Update(p)
+	}
+	return 0, false
+}
+
+// MACD is the difference of a fast and a slow EMA, with an EMA of it as signal line.
+// Its value is the MACD line and its Components are the signal line and the histogram
+type MACD struct {
+	fast      *EMA
+	slow      *EMA
+	signal    *EMA
+	line      float64
+	sig       float64
+	histogram float64
+}
+
+// NewMACD takes the usual 12, 26 and 9
+func NewMACD(fast int, slow int, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+func (m *MACD) Update(v float64) (float64, bool) {
+	f, ok1 := m.fast.Update(v)
+	s, ok2 := m.slow.Update(v)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	m.line = f - s
+	sig, ok := m.signal.Update(m.line)
+	if !ok {
+		return 0, false
+	}
+	m.sig, m.histogram = sig, m.line-sig
+	return m.line, true
+}
+
+func (m *MACD) Components() []float64 {
+	return []float64{m.sig, m.histogram}
+}
+
+func (m *MACD) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	return m.Update(bar.Close)
+}
+
+func (m *MACD) UpdateTick(t ibgo.Tick) (float64, bool) {
+	if p := price(t); p != 0 {
+		return m.Update(p)
+	}
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/jinspiration/ibgo"
+)
+
+// ATR is Wilder's average true range over n bars
+type ATR struct {
+	n         int
+	prevClose float64
+	count     int
+	value     float64
+}
+
+func NewATR(n int) *ATR {
+	return &ATR{n: n}
+}
+
+func (a *ATR) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	tr := bar.High - bar.Low
+	if a.count > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(bar.High-a.prevClose), math.Abs(bar.Low-a.prevClose)))
+	}
+	a.prevClose = bar.Close
+	a.count++
+	n := float64(a.n)
+	if a.count <= a.n {
+		a.value += tr / n
+		return a.value, a.count == a.n
+	}
+	a.value = (a.value*(n-1) + tr) / n
+	return a.value, true
+}
+
+// Bollinger bands are the SMA of the last n values, k population standard deviations either side.
+// Its value is the middle band and its Components are the upper and lower bands
+type Bollinger struct {
+	k     float64
+	w     window
+	sum   float64
+	sumsq float64
+	upper float64
+	lower float64
+}
+
+// NewBollinger takes the usual 20 and 2
+func NewBollinger(n int, k float64) *Bollinger {
+	return &Bollinger{k: k, w: newWindow(n)}
+}
+
+func (b *Bollinger) Update(v float64) (float64, bool) {
+	if old, evicted := b.w.push(v); evicted {
+		b.sum -= old
+		b.sumsq -= old * old
+	}
+	b.sum += v
+	b.sumsq += v * v
+	if !b.w.full {
+		return 0, false
+	}
+	n := float64(len(b.w.vals))
+	mean := b.sum / n
+	sd := math.Sqrt(math.Max(b.sumsq/n-mean*mean, 0))
+	b.upper, b.lower = mean+b.k*sd, mean-b.k*sd
+	return mean, true
+}
+
+func (b *Bollinger) Components() []float64 {
+	return []float64{b.upper, b.lower}
+}
+
+func (b *Bollinger) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	return b.Update(bar.Close)
+}
+
+func (b *Bollinger) UpdateTick(t ibgo.Tick) (float64, bool) {
+	if p := price(t); p != 0 {
+		return b.Update(p)
+	}
+	return 0, false
+}
+
+// RealizedVol is the sample standard deviation of the last n log returns, scaled by the square root of
+// periodsPerYear, such as 252 for daily bars, to annualise it. Zero leaves it per period
+type RealizedVol struct {
+	scale float64
+	prev  float64
+	w     window
+	sum   float64
+	sumsq float64
+}
+
+func NewRealizedVol(n int, periodsPerYear float64) *RealizedVol {
+	scale := 1.0
+	if periodsPerYear > 0 {
+		scale = math.Sqrt(periodsPerYear)
+	}
+	return &RealizedVol{scale: scale, w: newWindow(n)}
+}
+
+func (r *RealizedVol) Update(v float64) (float64, bool) {
+	prev := r.prev
+	r.prev = v
+	if prev <= 0 || v <= 0 {
+		return 0, false
+	}
+	ret := math.Log(v / prev)
+	if old, evicted := r.w.push(ret); evicted {
+		r.sum -= old
+		r.sumsq -= old * old
+	}
+	r.sum += ret
+	r.sumsq += ret * ret
+	n := float64(len(r.w.vals))
+	if !r.w.full || n < 2 {
+		return 0, false
+	}
+	variance := (r.sumsq - r.sum*r.sum/n) / (n - 1)
+	return math.Sqrt(math.Max(variance, 0)) * r.scale, true
+}
+
+func (r *RealizedVol) UpdateBar(bar ibgo.BarData) (float64, bool) {
+	return r.Update(bar.Close)
+}
+
+func (r *RealizedVol) UpdateTick(t ibgo.Tick) (float64, bool) {
+	if p := price(t); p != 0 {
+		return r.Update(p)
+	}
+	return 0, false
+}
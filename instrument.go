@@ -2,6 +2,9 @@ package ibgo
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -10,6 +13,12 @@ type Instrument struct {
 	Detail            ContractDetail
 	client            *IBClient
 	lastHistoricalReq time.Time
+	exchangeMu        sync.RWMutex
+	exchanges         SmartComponents
+	bboExchange       string
+	calendarOnce      sync.Once
+	tradingCal        *calendar
+	liquidCal         *calendar
 }
 
 func (ins *Instrument) Contract() Contract {
@@ -24,27 +33,58 @@ func newInstrument(cd ContractData, c *IBClient) (ins *Instrument) {
 	ins = &Instrument{contract: cd.Contract, Detail: cd.ContractDetail, client: c}
 	return
 }
+
+// IgnoredExchanges lists exchanges whose contract details are dropped when resolving an instrument
+var IgnoredExchanges = map[string]struct{}{
+	"QBALGO": {},
+}
+
+func filterIgnored(condatas []ContractData) []ContractData {
+	filtered := make([]ContractData, 0, len(condatas))
+	for _, condata := range condatas {
+		if _, ok := IgnoredExchanges[condata.Exchange]; !ok {
+			filtered = append(filtered, condata)
+		}
+	}
+	return filtered
+}
+
 func (c *IBClient) NewInstrument(contract Contract) (*Instrument, error) {
-	if contractDataList, err := c.ReqContractDetails(contract); err != nil {
+	condatas, err := c.ReqContractDetails(contract)
+	if err != nil {
 		return nil, err
-	} else if len(contractDataList) == 0 {
+	}
+	condatas = filterIgnored(condatas)
+	if len(condatas) == 0 {
 		return nil, fmt.Errorf("No contract found")
-	} else if len(contractDataList) != 1 {
+	} else if len(condatas) != 1 {
 		return nil, fmt.Errorf("There is ambiguity in the contract defination. Use IBClient.ReqSymbolSample method to look up")
-	} else {
-		contract := contractDataList[0]
-		return newInstrument(contract, c), nil
 	}
+	return newInstrument(condatas[0], c), nil
 }
 
 func (c *IBClient) NewInstrumentFromConID(id int) (*Instrument, error) {
 	return c.NewInstrument(Contract{ConID: int64(id)})
 }
 
+// ParseInstrument resolves a contract spec accepted by ParseContract
+func (c *IBClient) ParseInstrument(spec string) (*Instrument, error) {
+	con, err := ParseContract(spec)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewInstrument(con)
+}
+
 func (c *IBClient) USStock(symbol string) (*Instrument, error) {
 	return c.NewInstrument(Contract{Symbol: symbol, SecType: "STK", Currency: "USD", Exchange: "SMART"})
 }
 
+// Stock resolves a stock listed on exchange and routed through SMART
+func (c *IBClient) Stock(symbol string, exchange string, currency string) (*Instrument, error) {
+	return c.NewInstrument(Contract{Symbol: symbol, SecType: "STK", Currency: currency, Exchange: "SMART", PrimaryExchange: exchange})
+}
+
 func (c *IBClient) USFuture(symbol string, expiration string) (*Instrument, error) {
 	if to, ok := CommonFutureSymbolMap[symbol]; ok {
 		symbol = to
@@ -55,32 +95,168 @@ func (c *IBClient) USFuture(symbol string, expiration string) (*Instrument, erro
 	} else {
 		con = Contract{Symbol: symbol, SecType: "FUT", Currency: "USD", LastTradeDateOrContractMonth: expiration}
 	}
-	condatas, err := c.ReqContractDetails(con)
+	return c.NewInstrument(con)
+}
+
+// Forex resolves a currency pair written as EURUSD, EUR.USD or EUR/USD on IDEALPRO
+func (c *IBClient) Forex(pair string) (*Instrument, error) {
+	pair = strings.NewReplacer(".", "", "/", "").Replace(strings.ToUpper(pair))
+	if len(pair) != 6 {
+		return nil, fmt.Errorf("Bad currency pair %v", pair)
+	}
+	return c.NewInstrument(Contract{Symbol: pair[:3], SecType: "CASH", Currency: pair[3:], Exchange: "IDEALPRO"})
+}
+
+// Index resolves an index. An empty currency means USD
+func (c *IBClient) Index(symbol string, exchange string, currency string) (*Instrument, error) {
+	if currency == "" {
+		currency = "USD"
+	}
+	return c.NewInstrument(Contract{Symbol: symbol, SecType: "IND", Currency: currency, Exchange: exchange})
+}
+
+// CFD resolves a contract for difference routed through SMART. An empty currency means USD
+func (c *IBClient) CFD(symbol string, currency string) (*Instrument, error) {
+	if currency == "" {
+		currency = "USD"
+	}
+	return c.NewInstrument(Contract{Symbol: symbol, SecType: "CFD", Currency: currency, Exchange: "SMART"})
+}
+
+// Crypto resolves a crypto currency on PAXOS. An empty currency means USD
+func (c *IBClient) Crypto(symbol string, currency string) (*Instrument, error) {
+	if currency == "" {
+		currency = "USD"
+	}
+	return c.NewInstrument(Contract{Symbol: symbol, SecType: "CRYPTO", Currency: currency, Exchange: "PAXOS"})
+}
+
+// USOption resolves a US equity or index option routed through SMART
+func (c *IBClient) USOption(symbol string, expiration string, strike float64, right string) (*Instrument, error) {
+	return c.NewInstrument(Contract{Symbol: symbol, SecType: "OPT", Currency: "USD", Exchange: "SMART",
+		LastTradeDateOrContractMonth: expiration, Strike: strike, Right: right})
+}
+
+// USFutureOption resolves an option on a US future. An empty exchange is left to TWS
+func (c *IBClient) USFutureOption(symbol string, expiration string, strike float64, right string, exchange string) (*Instrument, error) {
+	if to, ok := CommonFutureSymbolMap[symbol]; ok {
+		symbol = to
+	}
+	return c.NewInstrument(Contract{Symbol: symbol, SecType: "FOP", Currency: "USD", Exchange: exchange,
+		LastTradeDateOrContractMonth: expiration, Strike: strike, Right: right})
+}
+
+// MarketRule returns the price increment rule of the exchange the instrument is routed to
+func (ins *Instrument) MarketRule() (*MarketRule, error) {
+	exchange := ins.contract.Exchange
+	if exchange == "" {
+		exchange = ins.contract.PrimaryExchange
+	}
+	id, err := ins.Detail.MarketRuleID(exchange)
 	if err != nil {
 		return nil, err
 	}
-	i := 0
-	for _, condata := range condatas {
-		if condata.Exchange != "QBALGO" {
-			condatas[i] = condata
-			i++
+	return ins.client.ReqMarketRule(id)
+}
+
+// RoundPrice moves price onto a valid tick in direction RoundNearest, RoundUp or RoundDown
+func (ins *Instrument) RoundPrice(price float64, direction int) (float64, error) {
+	if ins.Detail.MarketRuleIDs == "" {
+		return roundToIncrement(price, ins.Detail.MinTick, direction), nil
+	}
+	rule, err := ins.MarketRule()
+	if err != nil {
+		return 0, err
+	}
+	return rule.Round(price, direction), nil
+}
+
+// ValidPrice reports whether price sits on the increment ladder of the instrument
+func (ins *Instrument) ValidPrice(price float64) (bool, error) {
+	rounded, err := ins.RoundPrice(price, RoundNearest)
+	if err != nil {
+		return false, err
+	}
+	return math.Abs(rounded-price) < 1e-9, nil
+}
+
+// LoadExchangeMap fetches the components of bboExchange so that single letter exchange codes
+// in TickStream and HistoricalTicks are replaced by full exchange names. Streams load it from their tick
+// request parameters; HistoricalTicks never requests market data, so until a stream or a call here has loaded
+// the map it leaves codes untranslated. The BBO exchange comes with TickStream.Params
+func (ins *Instrument) LoadExchangeMap(bboExchange string) error {
+	components, err := ins.client.ReqSmartComponents(bboExchange)
+	if err != nil {
+		return err
+	}
+	valid := make(map[string]struct{})
+	for _, ex := range strings.Split(ins.Detail.ValidExchange, ",") {
+		valid[ex] = struct{}{}
+	}
+	m := make(SmartComponents)
+	for _, comp := range components {
+		// prefer exchanges the contract actually trades on when a letter is shared
+		if prev, ok := m[comp.ExchangeLetter]; ok {
+			if _, isValid := valid[prev.Exchange]; isValid {
+				continue
+			}
 		}
+		m[comp.ExchangeLetter] = comp
 	}
-	condatas = condatas[:i]
-	if len(condatas) == 0 {
-		return nil, fmt.Errorf("ContractData request end unexpected or cancelled by user")
+	ins.exchangeMu.Lock()
+	ins.exchanges, ins.bboExchange = m, bboExchange
+	ins.exchangeMu.Unlock()
+	return nil
+}
+
+// loadParams loads the exchange map announced by the tick request parameters of a subscription in the
+// background, so the stream delivering the params never waits on the smart components reply. The map only
+// names exchanges, so if it fails to load codes are left untranslated
+func (ins *Instrument) loadParams(p *TickReqParams) {
+	ins.exchangeMu.RLock()
+	loaded := ins.bboExchange == p.BBOExchange
+	ins.exchangeMu.RUnlock()
+	if loaded || p.BBOExchange == "" {
+		return
 	}
-	if len(condatas) == 1 {
-		return newInstrument(condatas[0], c), nil
-	} else {
-		return nil, fmt.Errorf("There is ambiguity in the contract defination. Use IBClient.ReqSymbolSample method to look up")
+	go ins.LoadExchangeMap(p.BBOExchange)
+}
+
+// paramsBox holds the tick request parameters a stream goroutine receives for its consumer to read
+type paramsBox struct {
+	mu sync.Mutex
+	p  *TickReqParams
+}
+
+func (b *paramsBox) set(p *TickReqParams) {
+	b.mu.Lock()
+	b.p = p
+	b.mu.Unlock()
+}
+
+func (b *paramsBox) get() *TickReqParams {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.p
+}
+
+// ExchangeName translates a single letter exchange code. Unknown codes are returned as is
+func (ins *Instrument) ExchangeName(code string) string {
+	ins.exchangeMu.RLock()
+	defer ins.exchangeMu.RUnlock()
+	if comp, ok := ins.exchanges[code]; ok {
+		return comp.Exchange
 	}
+	return code
 }
 
+// TickStream ends with Ticks closed, after Cancel or with the error in Err.
+// Params returns the tick request parameters once IB has sent them
 type TickStream struct {
 	Ticks  chan Tick
 	Cancel func() error
 	Err    error
+	Params func() *TickReqParams
 }
 
 func (ins *Instrument) TickStream(tickType string) (stream *TickStream, err error) {
@@ -100,16 +276,28 @@ func (ins *Instrument) TickStream(tickType string) (stream *TickStream, err erro
 	if err != nil {
 		return
 	}
-	stream = &TickStream{make(chan Tick), nil, nil}
-	stream.Cancel = func() error {
+	params := &paramsBox{}
+	stream = &TickStream{make(chan Tick), nil, nil, params.get}
+	done := make(chan struct{})
+	var once sync.Once
+	cancel := func() error {
 		return ins.client.reqCancel(outCANCELTICKBYTICKDATA, "", id)
 	}
+	stream.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return cancel()
+	}
 	go func() {
+		defer func() {
+			close(stream.Ticks)
+			ins.client.unregister(id, respCh)
+		}()
 		pending := make([]Tick, 0)
 		var first Tick
 		var update chan Tick
 		var last time.Time
 		i := 1
+		tagger := ins.newSessionTagger()
 		for {
 			if len(pending) > 0 {
 				first = pending[0]
@@ -118,11 +306,16 @@ func (ins *Instrument) TickStream(tickType string) (stream *TickStream, err erro
 			select {
 			case msg := <-respCh:
 				if msg.code[:1] == "E" {
-					close(stream.Ticks)
 					stream.Err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
 					return
 				}
+				if p, ok := (msg.body).(*TickReqParams); ok {
+					params.set(p)
+					ins.loadParams(p)
+					continue
+				}
 				t := *(msg.body).(*Tick)
+				t.Exchange = ins.ExchangeName(t.Exchange)
 				if t.Time.After(last) {
 					last = t.Time
 					i = 1
@@ -130,15 +323,19 @@ func (ins *Instrument) TickStream(tickType string) (stream *TickStream, err erro
 					fmt.Println("ERROR! Tick out of order")
 				} else {
 					if err := t.SetShift(i); err != nil {
-						close(stream.Ticks)
 						stream.Err = err
+						cancel()
+						return
 					}
 					i++
 				}
+				tagger.tag(&t)
 				pending = append(pending, t)
 			case update <- first:
 				pending = pending[1:]
 				update = nil
+			case <-done:
+				return
 			}
 		}
 	}()
@@ -156,7 +353,7 @@ func (ins *Instrument) reqHistorical() {
 	}
 }
 
-func (ins *Instrument) HeadTimeStamp(whatToShow string, useRTH bool) (t time.Time, err error) {
+func (ins *Instrument) HeadTimeStamp(whatToShow WhatToShow, useRTH bool) (t time.Time, err error) {
 	id, ack, respCh, err := ins.client.reqTicker()
 	if err != nil {
 		return
@@ -164,30 +361,41 @@ func (ins *Instrument) HeadTimeStamp(whatToShow string, useRTH bool) (t time.Tim
 	defer close(ack)
 	con := ins.contract
 	if con.SecType == "FUT" {
-		con.SecType = "CONTFUT"
+		con.IncludeExpired = true
 	}
 	w := ins.client.writer
 	w.writeString(outREQHEADTIMESTAMP)
 	w.writeString(id)
 	w.writeContractWithExpired(&con)
 	w.writeBool(useRTH)
-	w.writeString(whatToShow)
-	w.writeInt(1)
+	w.writeString(string(whatToShow))
+	w.writeInt(2)
 	err = w.send()
 	if err != nil {
 		return
 	}
 	msg := <-respCh
-	str := (msg.body).(string)
+	if msg.code[:1] == "E" {
+		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+		return
+	}
 	loc, err := time.LoadLocation(ins.Detail.TimeZoneID)
 	if err != nil {
 		return
 	}
-	t, err = time.ParseInLocation("20060102  15:04:00", str, loc)
+	t, err = parseIBTime((msg.body).(string), loc)
 	return
 }
 
-func (ins *Instrument) HistoricalTicks(startDataTime string, endDateTime string, numberOfTicks int64, whatToShow string, useRTH bool) (ticks []Tick, err error) {
+// HistoricalTicks returns ticks tagged with their session bits. Ticks sharing a second are spread apart
+// with SetShift like TickStream does
+func (ins *Instrument) HistoricalTicks(startDataTime string, endDateTime string, numberOfTicks int64, whatToShow WhatToShow, useRTH bool) (ticks []Tick, err error) {
+	if whatToShow == "BIDASK" {
+		whatToShow = BidAsk
+	}
+	if err = ValidateTickRequest(whatToShow); err != nil {
+		return
+	}
 	ins.reqHistorical()
 	<-ins.client.historical
 	id, ack, respCh, err := ins.client.reqTicker()
@@ -195,9 +403,6 @@ func (ins *Instrument) HistoricalTicks(startDataTime string, endDateTime string,
 		return
 	}
 	defer close(ack)
-	if whatToShow == "BIDASK" {
-		whatToShow = "BID_ASK"
-	}
 	con := ins.contract
 	if con.SecType == "CONTFUT" {
 		con.SecType = "FUT"
@@ -209,7 +414,7 @@ func (ins *Instrument) HistoricalTicks(startDataTime string, endDateTime string,
 	w.writeString(startDataTime)
 	w.writeString(endDateTime)
 	w.writeInt(numberOfTicks)
-	w.writeString(whatToShow)
+	w.writeString(string(whatToShow))
 	w.writeBool(useRTH)
 	w.writeBool(false)
 	w.writeString("")
@@ -223,10 +428,20 @@ func (ins *Instrument) HistoricalTicks(startDataTime string, endDateTime string,
 		return
 	}
 	ticks = (msg.body).([]Tick)
+	for i := range ticks {
+		ticks[i].Exchange = ins.ExchangeName(ticks[i].Exchange)
+	}
+	ins.TagTicks(ticks)
+	err = shiftTicks(ticks)
 	return
 }
 
-func (ins *Instrument) HistoricalBar(endDateTime string, durationStr string, barSize string, whatToShow string, useRTH bool, keepUpToDate bool) (bars []BarData, err error) {
+// HistoricalBar returns the bars with their times resolved, intraday from epoch seconds and daily in the instrument's
+// time zone, along with the start and end of the period IB served
+func (ins *Instrument) HistoricalBar(endDateTime string, durationStr Duration, barSize BarSize, whatToShow WhatToShow, useRTH bool, keepUpToDate bool) (bars []BarData, start time.Time, end time.Time, err error) {
+	if err = ValidateBarRequest(ins.contract.SecType, durationStr, barSize, whatToShow); err != nil {
+		return
+	}
 	ins.reqHistorical()
 	<-ins.client.historical
 	id, ack, respCh, err := ins.client.reqTicker()
@@ -236,7 +451,7 @@ func (ins *Instrument) HistoricalBar(endDateTime string, durationStr string, bar
 	defer close(ack)
 	con := ins.contract
 	if con.SecType == "FUT" {
-		con.SecType = "CONTFUT"
+		con.IncludeExpired = true
 	}
 	w := ins.client.writer
 	w.writeString(outREQHISTORICALDATA)
@@ -245,12 +460,12 @@ func (ins *Instrument) HistoricalBar(endDateTime string, durationStr string, bar
 	w.writeString(id)
 	w.writeContractWithExpired(&con)
 	w.writeString(endDateTime)
-	w.writeString(barSize)
-	w.writeString(durationStr)
+	w.writeString(string(barSize))
+	w.writeString(string(durationStr))
 	w.writeBool(useRTH)
-	w.writeString(whatToShow)
-	w.writeInt(1)
-	//TODO BAGS
+	w.writeString(string(whatToShow))
+	w.writeInt(2)
+	w.writeComboLegs(&con)
 	w.writeBool(keepUpToDate) // serverVersion 124
 	w.writeString("")
 	w.send()
@@ -259,7 +474,212 @@ func (ins *Instrument) HistoricalBar(endDateTime string, durationStr string, bar
 		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
 		return
 	}
-	bars = (msg.body).([]BarData)
+	loc, err := time.LoadLocation(ins.Detail.TimeZoneID)
+	if err != nil {
+		return
+	}
+	data := (msg.body).(*HistoricalData)
+	if err = data.parseTimes(loc); err != nil {
+		return
+	}
+	bars, start, end = data.Bars, data.StartDate, data.EndDate
+	return
+}
+
+// Histogram returns the price/size distribution over period such as "3 days" or "1 week"
+func (ins *Instrument) Histogram(useRTH bool, period string) (entries []HistogramEntry, err error) {
+	ins.reqHistorical()
+	<-ins.client.historical
+	id, ack, respCh, err := ins.client.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	con := ins.contract
+	if con.SecType == "CONTFUT" {
+		con.SecType = "FUT"
+	}
+	w := ins.client.writer
+	w.writeString(outREQHISTOGRAMDATA)
+	w.writeString(id)
+	w.writeContractWithExpired(&con)
+	w.writeBool(useRTH)
+	w.writeString(period)
+	err = w.send()
+	if err != nil {
+		return
+	}
+	msg := <-respCh
+	if msg.code[:1] == "E" {
+		err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+		return
+	}
+	entries = (msg.body).([]HistogramEntry)
+	return
+}
+
+func (ins *Instrument) HistoricalNews(providerCodes []string, start time.Time, end time.Time, totalResults int64) (news []HistoricalNews, hasMore bool, err error) {
+	id, ack, respCh, err := ins.client.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	w := ins.client.writer
+	w.writeString(outREQHISTORICALNEWS)
+	w.writeString(id)
+	w.writeInt(ins.contract.ConID)
+	w.writeString(strings.Join(providerCodes, "+"))
+	w.writeString(formatNewsTime(start))
+	w.writeString(formatNewsTime(end))
+	w.writeInt(totalResults)
+	w.writeString("") // serverVersion 128
+	err = w.send()
+	if err != nil {
+		return
+	}
+	news = make([]HistoricalNews, 0)
+	for msg := range respCh {
+		if msg.code[:1] == "E" {
+			err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+			return
+		}
+		if msg.code == inHISTORICALNEWSEND {
+			hasMore = (msg.body).(*HistoricalNewsEnd).HasMore
+			return
+		}
+		news = append(news, *(msg.body).(*HistoricalNews))
+	}
+	return
+}
+
+func formatNewsTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(HistoricalNewsTimeLayout)
+}
+
+// DeltaNeutral has IB validate the DeltaNeutralContract of a combo contract and returns the contract IB
+// validated it as. It is checked through a market data request with market data switched off, which is
+// cancelled once IB replies. Tick by tick streams and historical requests take no delta-neutral leg, and
+// orders are not placed by this package, so this is the one request the validation is surfaced for
+func (ins *Instrument) DeltaNeutral() (dn *DeltaNeutralContract, err error) {
+	if ins.contract.DeltaNeutralContract == nil {
+		return nil, fmt.Errorf("Contract has no delta-neutral contract")
+	}
+	id, ack, respCh, err := ins.client.reqTicker()
+	if err != nil {
+		return
+	}
+	w := ins.client.writer
+	w.writeString(outREQMKTDATA)
+	w.writeString("11")
+	w.writeString(id)
+	w.writeContract(&ins.contract)
+	w.writeComboLegs(&ins.contract)
+	w.writeDeltaNeutral(&ins.contract)
+	w.writeString("mdoff")
+	w.writeBool(false)
+	w.writeBool(false)
+	w.writeString("")
+	err = w.send()
+	close(ack)
+	if err != nil {
+		go ins.client.unregister(id, respCh)
+		return
+	}
+	defer func() {
+		ins.client.reqCancel(outCANCELMKTDATA, "2", id)
+		go ins.client.unregister(id, respCh)
+	}()
+	timeout := time.After(ReplyTimeout)
+	for {
+		select {
+		case msg := <-respCh:
+			if msg.code[:1] == "E" {
+				return nil, fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+			}
+			// tick request parameters may come first
+			if dn, ok := (msg.body).(*DeltaNeutralContract); ok {
+				return dn, nil
+			}
+		case <-timeout:
+			return nil, ErrNoReply
+		}
+	}
+}
+
+// NewsStream ends with News closed, after Cancel or with the error in Err
+type NewsStream struct {
+	News   chan TickNews
+	Cancel func() error
+	Err    error
+	Params func() *TickReqParams
+}
+
+// NewsStream subscribes to live headlines of the instrument through generic tick 292
+func (ins *Instrument) NewsStream(providerCodes []string) (stream *NewsStream, err error) {
+	id, ack, respCh, err := ins.client.reqTicker()
+	if err != nil {
+		return
+	}
+	defer close(ack)
+	w := ins.client.writer
+	w.writeString(outREQMKTDATA)
+	w.writeString("11")
+	w.writeString(id)
+	w.writeContract(&ins.contract)
+	w.writeComboLegs(&ins.contract)
+	w.writeDeltaNeutral(&ins.contract)
+	w.writeString("mdoff,292:" + strings.Join(providerCodes, "+"))
+	w.writeBool(false)
+	w.writeBool(false)
+	w.writeString("")
+	err = w.send()
+	if err != nil {
+		return
+	}
+	params := &paramsBox{}
+	stream = &NewsStream{make(chan TickNews), nil, nil, params.get}
+	done := make(chan struct{})
+	var once sync.Once
+	stream.Cancel = func() error {
+		once.Do(func() { close(done) })
+		return ins.client.reqCancel(outCANCELMKTDATA, "2", id)
+	}
+	go func() {
+		defer func() {
+			close(stream.News)
+			ins.client.unregister(id, respCh)
+		}()
+		pending := make([]TickNews, 0)
+		var first TickNews
+		var update chan TickNews
+		for {
+			if len(pending) > 0 {
+				first = pending[0]
+				update = stream.News
+			}
+			select {
+			case msg := <-respCh:
+				if msg.code[:1] == "E" {
+					stream.Err = fmt.Errorf("Error%v: %v", msg.code[1:], msg.body)
+					return
+				}
+				switch body := (msg.body).(type) {
+				case *TickNews:
+					pending = append(pending, *body)
+				case *TickReqParams:
+					params.set(body)
+				}
+			case update <- first:
+				pending = pending[1:]
+				update = nil
+			case <-done:
+				return
+			}
+		}
+	}()
 	return
 }
 
@@ -0,0 +1,91 @@
+package ibgo
+
+import (
+	"testing"
+)
+
+// cachedClient answers contract details from a cache alone, each row for its contract without the ConID.
+// A contract not in it fails with ErrDisconnected
+func cachedClient(t *testing.T, rows ...ContractData) *IBClient {
+	cache, err := NewContractCache(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		spec := row.Contract
+		spec.ConID = 0
+		cache.Put(spec, []ContractData{row})
+	}
+	c := &IBClient{}
+	c.UseContractCache(cache)
+	return c
+}
+
+func TestInstrumentHelpers(t *testing.T) {
+	rows := []Contract{
+		{ConID: 1, Symbol: "EUR", SecType: "CASH", Currency: "USD", Exchange: "IDEALPRO"},
+		{ConID: 2, Symbol: "SPX", SecType: "IND", Currency: "USD", Exchange: "CBOE"},
+		{ConID: 3, Symbol: "IBUS500", SecType: "CFD", Currency: "USD", Exchange: "SMART"},
+		{ConID: 4, Symbol: "BTC", SecType: "CRYPTO", Currency: "USD", Exchange: "PAXOS"},
+		{ConID: 5, Symbol: "SAP", SecType: "STK", Currency: "EUR", Exchange: "SMART", PrimaryExchange: "IBIS"},
+		{ConID: 6, Symbol: "AAPL", SecType: "OPT", Currency: "USD", Exchange: "SMART", LastTradeDateOrContractMonth: "20240119", Strike: 150, Right: "C"},
+		{ConID: 7, Symbol: "EUR", SecType: "FOP", Currency: "USD", Exchange: "CME", LastTradeDateOrContractMonth: "20240105", Strike: 1.1, Right: "P"},
+	}
+	condatas := make([]ContractData, len(rows))
+	for i, row := range rows {
+		condatas[i].Contract = row
+	}
+	c := cachedClient(t, condatas...)
+	tests := []struct {
+		name  string
+		get   func() (*Instrument, error)
+		conID int64
+	}{
+		{"Forex EURUSD", func() (*Instrument, error) { return c.Forex("EURUSD") }, 1},
+		{"Forex eur.usd", func() (*Instrument, error) { return c.Forex("eur.usd") }, 1},
+		{"Forex EUR/USD", func() (*Instrument, error) { return c.Forex("EUR/USD") }, 1},
+		{"Index", func() (*Instrument, error) { return c.Index("SPX", "CBOE", "") }, 2},
+		{"CFD", func() (*Instrument, error) { return c.CFD("IBUS500", "") }, 3},
+		{"Crypto", func() (*Instrument, error) { return c.Crypto("BTC", "") }, 4},
+		{"Stock", func() (*Instrument, error) { return c.Stock("SAP", "IBIS", "EUR") }, 5},
+		{"USOption", func() (*Instrument, error) { return c.USOption("AAPL", "20240119", 150, "C") }, 6},
+		{"USFutureOption", func() (*Instrument, error) { return c.USFutureOption("6E", "20240105", 1.1, "P", "CME") }, 7},
+	}
+	for _, tt := range tests {
+		ins, err := tt.get()
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+		} else if ins.ConID() != tt.conID {
+			t.Errorf("%v resolved %v, want %v", tt.name, ins.ConID(), tt.conID)
+		}
+	}
+	for _, pair := range []string{"EURUS", "EUR/USDX", ""} {
+		if _, err := c.Forex(pair); err == nil || err == ErrDisconnected {
+			t.Errorf("Forex(%q) = %v, want a bad pair error", pair, err)
+		}
+	}
+}
+
+func TestNewInstrumentIgnoresQBALGO(t *testing.T) {
+	spec := Contract{Symbol: "ES", SecType: "CONTFUT"}
+	tests := []struct {
+		rows  []ContractData
+		conID int64
+		ok    bool
+	}{
+		{[]ContractData{contractRow(1, "GLOBEX"), contractRow(2, "QBALGO")}, 1, true},
+		{[]ContractData{contractRow(2, "QBALGO"), contractRow(1, "GLOBEX")}, 1, true},
+		{[]ContractData{contractRow(2, "QBALGO")}, 0, false},
+		{[]ContractData{contractRow(1, "GLOBEX"), contractRow(3, "CME")}, 0, false},
+	}
+	for _, tt := range tests {
+		c := cachedClient(t)
+		c.contractCache.Put(spec, tt.rows)
+		ins, err := c.NewInstrument(spec)
+		if (err == nil) != tt.ok || err == ErrDisconnected {
+			t.Errorf("NewInstrument over %v: %v, want ok %v", tt.rows, err, tt.ok)
+		} else if tt.ok && ins.ConID() != tt.conID {
+			t.Errorf("NewInstrument over %v resolved %v, want %v", tt.rows, ins.ConID(), tt.conID)
+		}
+	}
+}
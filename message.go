@@ -1,6 +1,10 @@
 package ibgo
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,4 +32,126 @@ type HistoricalData struct {
 	EndDateStr   string
 	ItemCount    int64
 	Bars         []BarData
+	StartDate    time.Time
+	EndDate      time.Time
+	barTimes     []string
 }
+
+// parseTimes resolves the bar, start and end times. Dates and local date times are read in loc
+func (h *HistoricalData) parseTimes(loc *time.Location) (err error) {
+	for i, str := range h.barTimes {
+		if h.Bars[i].Time, err = parseIBTime(str, loc); err != nil {
+			return
+		}
+	}
+	if h.StartDateStr != "" {
+		if h.StartDate, err = parseIBTime(h.StartDateStr, loc); err != nil {
+			return
+		}
+	}
+	if h.EndDateStr != "" {
+		h.EndDate, err = parseIBTime(h.EndDateStr, loc)
+	}
+	return
+}
+
+// parseIBTime reads the time formats found in historical responses: epoch seconds,
+// dates, and date times with or without a trailing time zone
+func parseIBTime(str string, loc *time.Location) (time.Time, error) {
+	fields := strings.Fields(str)
+	switch {
+	case len(fields) == 1 && len(str) == 8:
+		return time.ParseInLocation("20060102", str, loc)
+	case len(fields) == 1 && strings.Contains(str, "-"):
+		return time.ParseInLocation("20060102-15:04:05", str, time.UTC)
+	case len(fields) == 1:
+		u, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(u, 0), nil
+	case len(fields) == 3:
+		l, err := time.LoadLocation(fields[2])
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	case len(fields) != 2:
+		return time.Time{}, fmt.Errorf("Unknown time format %q", str)
+	}
+	return time.ParseInLocation("20060102 15:04:05", fields[0]+" "+fields[1], loc)
+}
+
+type FamilyCode struct {
+	AccountID  string
+	FamilyCode string
+}
+
+type SoftDollarTier struct {
+	Name        string
+	Value       string
+	DisplayName string
+}
+
+type ReceiveFA struct {
+	FADataType int64
+	XML        string
+}
+
+type TickReqParams struct {
+	MinTick             float64
+	BBOExchange         string
+	SnapshotPermissions int64
+}
+
+type NewsProvider struct {
+	Code string
+	Name string
+}
+
+type HistoricalNews struct {
+	Time         time.Time
+	ProviderCode string
+	ArticleID    string
+	Headline     string
+}
+
+type HistoricalNewsEnd struct {
+	HasMore bool
+}
+
+// NewsArticle type
+const (
+	NewsArticleText int64 = iota
+	NewsArticleBinary
+)
+
+type NewsArticle struct {
+	Type int64
+	Text string
+}
+
+// PDF decodes the base64 body of a binary article
+func (a *NewsArticle) PDF() ([]byte, error) {
+	if a.Type != NewsArticleBinary {
+		return nil, fmt.Errorf("Article is not binary")
+	}
+	return base64.StdEncoding.DecodeString(a.Text)
+}
+
+type TickNews struct {
+	Time         time.Time
+	ProviderCode string
+	ArticleID    string
+	Headline     string
+	ExtraData    string
+}
+
+type NewsBulletin struct {
+	MsgID      int64
+	MsgType    int64
+	Message    string
+	OriginExch string
+}
+
+var HistoricalNewsTimeLayout = "2006-01-02 15:04:05.0"
@@ -0,0 +1,93 @@
+package ibgo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// fieldReader reads fields off a buffer holding a whole message, without a connection
+func fieldReader(fields ...string) *msgReader {
+	b := []byte(strings.Join(fields, "\x00") + "\x00")
+	return &msgReader{buf: b, w: len(b), curSize: len(b)}
+}
+
+func TestParseIBTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		str  string
+		loc  *time.Location
+		want time.Time
+		ok   bool
+	}{
+		{"20240109", ny, time.Date(2024, 1, 9, 0, 0, 0, 0, ny), true},
+		{"20240109", time.UTC, time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC), true},
+		{"20240109 09:30:00", ny, time.Date(2024, 1, 9, 9, 30, 0, 0, ny), true},
+		{"20240109  09:30:00", london, time.Date(2024, 1, 9, 9, 30, 0, 0, london), true},
+		// a trailing zone overrides loc
+		{"20240109 09:30:00 America/New_York", london, time.Date(2024, 1, 9, 9, 30, 0, 0, ny), true},
+		{"20240709 09:30:00 Europe/London", ny, time.Date(2024, 7, 9, 8, 30, 0, 0, time.UTC), true},
+		// the dashed form is UTC
+		{"20240109-14:30:00", ny, time.Date(2024, 1, 9, 14, 30, 0, 0, time.UTC), true},
+		{"1704810600", ny, time.Date(2024, 1, 9, 14, 30, 0, 0, time.UTC), true},
+		{"20240109 09:30:00 Mars/Olympus", ny, time.Time{}, false},
+		{"20240109 9.30", ny, time.Time{}, false},
+		{"2024-01-09", ny, time.Time{}, false},
+		{"Jan 9 2024 09:30", ny, time.Time{}, false},
+		{"", ny, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseIBTime(tt.str, tt.loc)
+		if (err == nil) != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseIBTime(%q, %v) = %v, %v, want %v, ok %v", tt.str, tt.loc, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecodeHistoricalData(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	rd := fieldReader(inHISTORICALDATA, "5", "20240109 09:30:00", "20240110 16:00:00", "2",
+		"20240109 09:30:00", "10", "11", "9.5", "10.5", "100", "10.25", "7",
+		"20240110 16:00:00 America/Chicago", "20", "21", "19.5", "20.5", "200", "20.25", "9")
+	m := &Message{}
+	decodeHistoricalData(m, rd)
+	h := m.body.(*HistoricalData)
+	if err := h.parseTimes(ny); err != nil {
+		t.Fatal(err)
+	}
+	if m.id != "5" || h.ItemCount != 2 || len(h.Bars) != 2 {
+		t.Fatalf("decoded id %v with %v of %v bars", m.id, len(h.Bars), h.ItemCount)
+	}
+	if want := time.Date(2024, 1, 9, 9, 30, 0, 0, ny); !h.StartDate.Equal(want) {
+		t.Errorf("StartDate %v, want %v", h.StartDate, want)
+	}
+	if want := time.Date(2024, 1, 10, 16, 0, 0, 0, ny); !h.EndDate.Equal(want) {
+		t.Errorf("EndDate %v, want %v", h.EndDate, want)
+	}
+	want := []BarData{
+		{Time: time.Date(2024, 1, 9, 9, 30, 0, 0, ny), Open: 10, High: 11, Low: 9.5, Close: 10.5, Volume: 100, WAP: 10.25, TradeCount: 7},
+		{Time: time.Date(2024, 1, 10, 17, 0, 0, 0, ny), Open: 20, High: 21, Low: 19.5, Close: 20.5, Volume: 200, WAP: 20.25, TradeCount: 9},
+	}
+	for i, bar := range h.Bars {
+		w := want[i]
+		if !bar.Time.Equal(w.Time) || bar.Open != w.Open || bar.High != w.High || bar.Low != w.Low || bar.Close != w.Close ||
+			bar.Volume != w.Volume || bar.WAP != w.WAP || bar.TradeCount != w.TradeCount {
+			t.Errorf("bar %v = %+v, want %+v", i, bar, w)
+		}
+	}
+	rd = fieldReader(inHISTORICALDATA, "6", "", "", "1", "2024-01-09", "1", "1", "1", "1", "1", "1", "1")
+	decodeHistoricalData(m, rd)
+	if err := m.body.(*HistoricalData).parseTimes(ny); err == nil {
+		t.Error("parseTimes read a bar time of unknown format")
+	}
+}
@@ -0,0 +1,234 @@
+package ibgo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BarSize string
+
+const (
+	Bar1Sec   BarSize = "1 secs"
+	Bar5Secs  BarSize = "5 secs"
+	Bar10Secs BarSize = "10 secs"
+	Bar15Secs BarSize = "15 secs"
+	Bar30Secs BarSize = "30 secs"
+	Bar1Min   BarSize = "1 min"
+	Bar2Mins  BarSize = "2 mins"
+	Bar3Mins  BarSize = "3 mins"
+	Bar5Mins  BarSize = "5 mins"
+	Bar10Mins BarSize = "10 mins"
+	Bar15Mins BarSize = "15 mins"
+	Bar20Mins BarSize = "20 mins"
+	Bar30Mins BarSize = "30 mins"
+	Bar1Hour  BarSize = "1 hour"
+	Bar2Hours BarSize = "2 hours"
+	Bar3Hours BarSize = "3 hours"
+	Bar4Hours BarSize = "4 hours"
+	Bar8Hours BarSize = "8 hours"
+	Bar1Day   BarSize = "1 day"
+	Bar1Week  BarSize = "1 week"
+	Bar1Month BarSize = "1 month"
+)
+
+var barSizeLength = map[BarSize]time.Duration{
+	Bar1Sec:   time.Second,
+	Bar5Secs:  time.Second * 5,
+	Bar10Secs: time.Second * 10,
+	Bar15Secs: time.Second * 15,
+	Bar30Secs: time.Second * 30,
+	Bar1Min:   time.Minute,
+	Bar2Mins:  time.Minute * 2,
+	Bar3Mins:  time.Minute * 3,
+	Bar5Mins:  time.Minute * 5,
+	Bar10Mins: time.Minute * 10,
+	Bar15Mins: time.Minute * 15,
+	Bar20Mins: time.Minute * 20,
+	Bar30Mins: time.Minute * 30,
+	Bar1Hour:  time.Hour,
+	Bar2Hours: time.Hour * 2,
+	Bar3Hours: time.Hour * 3,
+	Bar4Hours: time.Hour * 4,
+	Bar8Hours: time.Hour * 8,
+	Bar1Day:   time.Hour * 24,
+	Bar1Week:  time.Hour * 24 * 7,
+	Bar1Month: time.Hour * 24 * 30,
+}
+
+// Length returns the nominal span of one bar
+func (b BarSize) Length() (time.Duration, error) {
+	if d, ok := barSizeLength[b]; ok {
+		return d, nil
+	}
+	return 0, fmt.Errorf("Unknown bar size %q", string(b))
+}
+
+// Duration is an IB duration string such as "3600 S", "2 D" or "1 Y"
+type Duration string
+
+// DurationOf renders the shortest Duration covering d
+func DurationOf(d time.Duration) Duration {
+	if d <= time.Hour*24 {
+		secs := int64((d + time.Second - 1) / time.Second)
+		return Duration(fmt.Sprintf("%d S", secs))
+	}
+	days := int64((d + time.Hour*24 - 1) / (time.Hour * 24))
+	if days > 365 {
+		return Duration(fmt.Sprintf("%d Y", (days+364)/365))
+	}
+	return Duration(fmt.Sprintf("%d D", days))
+}
+
+var durationUnits = map[string]time.Duration{
+	"S": time.Second,
+	"D": time.Hour * 24,
+	"W": time.Hour * 24 * 7,
+	"M": time.Hour * 24 * 30,
+	"Y": time.Hour * 24 * 365,
+}
+
+// Length returns the approximate span of the duration, months counting 30 days and years 365
+func (d Duration) Length() (time.Duration, error) {
+	sl := strings.Split(string(d), " ")
+	if len(sl) != 2 {
+		return 0, fmt.Errorf("Bad duration %q", string(d))
+	}
+	n, err := strconv.ParseInt(sl[0], 10, 64)
+	unit, ok := durationUnits[sl[1]]
+	if err != nil || !ok || n <= 0 {
+		return 0, fmt.Errorf("Bad duration %q", string(d))
+	}
+	return time.Duration(n) * unit, nil
+}
+
+type WhatToShow string
+
+const (
+	Trades                  WhatToShow = "TRADES"
+	Midpoint                WhatToShow = "MIDPOINT"
+	Bid                     WhatToShow = "BID"
+	Ask                     WhatToShow = "ASK"
+	BidAsk                  WhatToShow = "BID_ASK"
+	AdjustedLast            WhatToShow = "ADJUSTED_LAST"
+	HistoricalVolatility    WhatToShow = "HISTORICAL_VOLATILITY"
+	OptionImpliedVolatility WhatToShow = "OPTION_IMPLIED_VOLATILITY"
+	RebateRate              WhatToShow = "REBATE_RATE"
+	FeeRate                 WhatToShow = "FEE_RATE"
+	YieldBid                WhatToShow = "YIELD_BID"
+	YieldAsk                WhatToShow = "YIELD_ASK"
+	YieldBidAsk             WhatToShow = "YIELD_BID_ASK"
+	YieldLast               WhatToShow = "YIELD_LAST"
+	AggTrades               WhatToShow = "AGGTRADES"
+	Schedule                WhatToShow = "SCHEDULE"
+)
+
+var quoteWhatToShow = []WhatToShow{Midpoint, Bid, Ask, BidAsk}
+
+// WhatToShowBySecType lists the bar types IB serves per security type
+var WhatToShowBySecType = map[string][]WhatToShow{
+	"STK":     append([]WhatToShow{Trades, AdjustedLast, HistoricalVolatility, OptionImpliedVolatility, RebateRate, FeeRate, Schedule}, quoteWhatToShow...),
+	"ETF":     append([]WhatToShow{Trades, AdjustedLast, HistoricalVolatility, OptionImpliedVolatility, Schedule}, quoteWhatToShow...),
+	"OPT":     append([]WhatToShow{Trades}, quoteWhatToShow...),
+	"FOP":     append([]WhatToShow{Trades}, quoteWhatToShow...),
+	"FUT":     append([]WhatToShow{Trades, Schedule}, quoteWhatToShow...),
+	"CONTFUT": append([]WhatToShow{Trades, Schedule}, quoteWhatToShow...),
+	"BAG":     append([]WhatToShow{Trades}, quoteWhatToShow...),
+	"IND":     {Trades, HistoricalVolatility, OptionImpliedVolatility, Schedule},
+	"CASH":    append([]WhatToShow{Schedule}, quoteWhatToShow...),
+	"CFD":     append([]WhatToShow{Schedule}, quoteWhatToShow...),
+	"CMDTY":   append([]WhatToShow{Schedule}, quoteWhatToShow...),
+	"CRYPTO":  append([]WhatToShow{AggTrades, Schedule}, quoteWhatToShow...),
+	"BOND":    append([]WhatToShow{YieldBid, YieldAsk, YieldBidAsk, YieldLast, Schedule}, quoteWhatToShow...),
+}
+
+// TickWhatToShow lists the types served by historical and live tick requests
+var TickWhatToShow = []WhatToShow{Trades, Midpoint, BidAsk}
+
+// durationSteps is IB's table of bar sizes allowed per requested duration
+var durationSteps = []struct {
+	upTo     time.Duration
+	min, max BarSize
+}{
+	{time.Second * 60, Bar1Sec, Bar1Min},
+	{time.Second * 120, Bar1Sec, Bar2Mins},
+	{time.Second * 1800, Bar1Sec, Bar30Mins},
+	{time.Second * 3600, Bar5Secs, Bar1Hour},
+	{time.Second * 14400, Bar10Secs, Bar3Hours},
+	{time.Second * 28800, Bar30Secs, Bar8Hours},
+	{time.Hour * 24, Bar1Min, Bar1Day},
+	{time.Hour * 24 * 2, Bar2Mins, Bar1Day},
+	{time.Hour * 24 * 7, Bar3Mins, Bar1Week},
+	{time.Hour * 24 * 31, Bar30Mins, Bar1Month},
+	{time.Hour * 24 * 366, Bar1Day, Bar1Month},
+}
+
+// ValidateBarRequest checks that duration, barSize and whatToShow form a request IB accepts for secType
+func ValidateBarRequest(secType string, duration Duration, barSize BarSize, whatToShow WhatToShow) error {
+	barLen, err := barSize.Length()
+	if err != nil {
+		return err
+	}
+	durLen, err := duration.Length()
+	if err != nil {
+		return err
+	}
+	minBar, maxBar := Bar1Day, Bar1Month
+	for _, step := range durationSteps {
+		if durLen <= step.upTo {
+			minBar, maxBar = step.min, step.max
+			break
+		}
+	}
+	if barLen < barSizeLength[minBar] || barLen > barSizeLength[maxBar] {
+		return fmt.Errorf("Bar size %q is not allowed for duration %q, use %q to %q", barSize, duration, minBar, maxBar)
+	}
+	return validWhatToShow(secType, whatToShow)
+}
+
+// minDurationFor returns the shortest duration IB accepts with barSize
+func minDurationFor(barSize BarSize) time.Duration {
+	barLen := barSizeLength[barSize]
+	for _, step := range durationSteps {
+		if barLen >= barSizeLength[step.min] && barLen <= barSizeLength[step.max] {
+			return step.upTo
+		}
+	}
+	return durationSteps[len(durationSteps)-1].upTo
+}
+
+func validWhatToShow(secType string, whatToShow WhatToShow) error {
+	allowed, ok := WhatToShowBySecType[secType]
+	if !ok {
+		return nil
+	}
+	for _, w := range allowed {
+		if w == whatToShow {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not available for %v", whatToShow, secType)
+}
+
+// ValidateTickRequest checks that whatToShow can be asked for in tick requests
+func ValidateTickRequest(whatToShow WhatToShow) error {
+	for _, w := range TickWhatToShow {
+		if w == whatToShow {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not available for ticks", whatToShow)
+}
+
+// FormatDateTime renders t the way IB expects date times, in t's own time zone.
+// Times in the local zone are sent in GMT since TWS may run elsewhere
+func FormatDateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if name := t.Location().String(); name != "Local" && name != "UTC" && name != "" {
+		return t.Format("20060102 15:04:05") + " " + name
+	}
+	return t.UTC().Format("20060102 15:04:05") + " GMT"
+}
@@ -0,0 +1,166 @@
+package ibgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationOf(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want Duration
+	}{
+		{time.Second, "1 S"},
+		{time.Millisecond * 1500, "2 S"},
+		{time.Hour, "3600 S"},
+		{time.Hour * 24, "86400 S"},
+		{time.Hour*24 + time.Second, "2 D"},
+		{time.Hour * 24 * 7, "7 D"},
+		{time.Hour * 24 * 365, "365 D"},
+		{time.Hour * 24 * 366, "2 Y"},
+		{time.Hour * 24 * 365 * 3, "3 Y"},
+	}
+	for _, tt := range tests {
+		if got := DurationOf(tt.d); got != tt.want {
+			t.Errorf("DurationOf(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+		got, err := DurationOf(tt.d).Length()
+		if err != nil {
+			t.Errorf("DurationOf(%v).Length() error: %v", tt.d, err)
+		} else if got < tt.d {
+			t.Errorf("DurationOf(%v) only covers %v", tt.d, got)
+		}
+	}
+}
+
+func TestDurationLength(t *testing.T) {
+	tests := []struct {
+		d    Duration
+		want time.Duration
+		ok   bool
+	}{
+		{"60 S", time.Minute, true},
+		{"2 D", time.Hour * 48, true},
+		{"1 W", time.Hour * 24 * 7, true},
+		{"1 M", time.Hour * 24 * 30, true},
+		{"1 Y", time.Hour * 24 * 365, true},
+		{"0 D", 0, false},
+		{"-1 D", 0, false},
+		{"1 H", 0, false},
+		{"1D", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.d.Length()
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("Duration(%q).Length() = %v, %v, want %v, ok %v", tt.d, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidateBarRequest(t *testing.T) {
+	tests := []struct {
+		secType    string
+		duration   Duration
+		barSize    BarSize
+		whatToShow WhatToShow
+		ok         bool
+	}{
+		{"STK", "60 S", Bar1Sec, Trades, true},
+		{"STK", "60 S", Bar2Mins, Trades, false},
+		{"STK", "3600 S", Bar1Sec, Trades, false},
+		{"STK", "3600 S", Bar5Secs, Midpoint, true},
+		{"STK", "1 D", Bar1Min, Trades, true},
+		{"STK", "1 D", Bar30Secs, Trades, false},
+		{"STK", "1 W", Bar3Mins, BidAsk, true},
+		{"STK", "1 M", Bar30Mins, AdjustedLast, true},
+		{"STK", "1 M", Bar15Mins, Trades, false},
+		{"STK", "1 Y", Bar1Day, Trades, true},
+		{"STK", "1 Y", Bar1Hour, Trades, false},
+		{"STK", "5 Y", Bar1Month, Trades, true},
+		{"CASH", "1 D", Bar1Min, Trades, false},
+		{"CASH", "1 D", Bar1Min, Midpoint, true},
+		{"IND", "1 D", Bar1Min, Bid, false},
+		{"FUT", "2 D", Bar5Mins, Trades, true},
+		{"XYZ", "1 D", Bar1Min, FeeRate, true},
+		{"STK", "1 D", "7 mins", Trades, false},
+		{"STK", "1 Q", Bar1Min, Trades, false},
+	}
+	for _, tt := range tests {
+		err := ValidateBarRequest(tt.secType, tt.duration, tt.barSize, tt.whatToShow)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateBarRequest(%q, %q, %q, %q) = %v, want ok %v", tt.secType, tt.duration, tt.barSize, tt.whatToShow, err, tt.ok)
+		}
+	}
+}
+
+func TestMinDurationFor(t *testing.T) {
+	for barSize := range barSizeLength {
+		d := minDurationFor(barSize)
+		if err := ValidateBarRequest("STK", DurationOf(d), barSize, Trades); err != nil {
+			t.Errorf("minDurationFor(%q) = %v is not accepted: %v", barSize, d, err)
+		}
+	}
+}
+
+func TestBarSizeLength(t *testing.T) {
+	tests := []struct {
+		b    BarSize
+		want time.Duration
+		ok   bool
+	}{
+		{Bar1Sec, time.Second, true},
+		{Bar30Mins, time.Minute * 30, true},
+		{Bar1Month, time.Hour * 24 * 30, true},
+		{"1 sec", 0, false},
+		{"1min", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.b.Length()
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("BarSize(%q).Length() = %v, %v, want %v, ok %v", tt.b, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidateTickRequest(t *testing.T) {
+	tests := []struct {
+		whatToShow WhatToShow
+		ok         bool
+	}{
+		{Trades, true},
+		{Midpoint, true},
+		{BidAsk, true},
+		{Bid, false},
+		{AdjustedLast, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateTickRequest(tt.whatToShow); (err == nil) != tt.ok {
+			t.Errorf("ValidateTickRequest(%q) = %v, want ok %v", tt.whatToShow, err, tt.ok)
+		}
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Time{}, ""},
+		{time.Date(2024, 1, 9, 9, 30, 0, 0, ny), "20240109 09:30:00 America/New_York"},
+		{time.Date(2024, 1, 9, 14, 30, 0, 0, time.UTC), "20240109 14:30:00 GMT"},
+		{time.Date(2024, 1, 9, 14, 30, 0, 0, time.UTC).Local(), "20240109 14:30:00 GMT"},
+		{time.Date(2024, 1, 9, 14, 30, 0, 999, time.UTC), "20240109 14:30:00 GMT"},
+	}
+	for _, tt := range tests {
+		if got := FormatDateTime(tt.t); got != tt.want {
+			t.Errorf("FormatDateTime(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
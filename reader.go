@@ -166,16 +166,16 @@ func (rd *msgReader) readUnix() time.Time {
 	return time.Unix(u, 0)
 }
 
-func (rd *msgReader) readBar() (bar BarData) {
+// readBar leaves the time as read since it can only be resolved with the time zone of the request
+func (rd *msgReader) readBar() (bar BarData, t string) {
 	bar = BarData{}
-	bar.Time = rd.readString()
+	t = rd.readString()
 	bar.Open = rd.readFloat()
 	bar.High = rd.readFloat()
 	bar.Low = rd.readFloat()
 	bar.Close = rd.readFloat()
 	bar.Volume = rd.readInt()
-	rd.discard()
-	// bar.Average = rd.readFloat()
+	bar.WAP = rd.readFloat()
 	bar.TradeCount = rd.readInt()
 	return
 }
@@ -254,18 +254,36 @@ func (rd *msgReader) readSessions(layout string, zone string) []Session {
 type decoderFunc = func(*Message, *msgReader)
 
 var decoderMap = map[string]decoderFunc{
-	inNEXTVALIDID:           decodeNextValidID,
-	inMANAGEDACCTS:          decodeManagedAccounts,
-	inCURRENTTIME:           decodeCurrentTime,
-	inERRMSG:                decodeErrorMsg,
-	inCONTRACTDATA:          decodeContractData,
-	inCONTRACTDATAEND:       decodeContractDataEnd,
-	inTICKBYTICK:            decodeTickByTick,
-	inHEADTIMESTAMP:         decodeHeadTimeStamp,
-	inHISTORICALDATA:        decodeHistoricalData,
-	inHISTORICALTICKS:       decodeHistoricalTick,
-	inHISTORICALTICKSLAST:   decodeHistoricalTick,
-	inHISTORICALTICKSBIDASK: decodeHistoricalTick,
+	inNEXTVALIDID:            decodeNextValidID,
+	inMANAGEDACCTS:           decodeManagedAccounts,
+	inCURRENTTIME:            decodeCurrentTime,
+	inERRMSG:                 decodeErrorMsg,
+	inCONTRACTDATA:           decodeContractData,
+	inCONTRACTDATAEND:        decodeContractDataEnd,
+	inBONDCONTRACTDATA:       decodeBondContractData,
+	inTICKBYTICK:             decodeTickByTick,
+	inHEADTIMESTAMP:          decodeHeadTimeStamp,
+	inHISTORICALDATA:         decodeHistoricalData,
+	inHISTORICALTICKS:        decodeHistoricalTick,
+	inHISTORICALTICKSLAST:    decodeHistoricalTick,
+	inHISTORICALTICKSBIDASK:  decodeHistoricalTick,
+	inNEWSPROVIDERS:          decodeNewsProviders,
+	inHISTORICALNEWS:         decodeHistoricalNews,
+	inHISTORICALNEWSEND:      decodeHistoricalNewsEnd,
+	inNEWSARTICLE:            decodeNewsArticle,
+	inTICKNEWS:               decodeTickNews,
+	inNEWSBULLETINS:          decodeNewsBulletins,
+	inHISTOGRAMDATA:          decodeHistogramData,
+	inMARKETRULE:             decodeMarketRule,
+	inSMARTCOMPONENTS:        decodeSmartComponents,
+	inTICKREQPARAMS:          decodeTickReqParams,
+	inFAMILYCODES:            decodeFamilyCodes,
+	inSOFTDOLLARTIERS:        decodeSoftDollarTiers,
+	inRECEIVEFA:              decodeReceiveFA,
+	inREPLACEFAEND:           decodeReplaceFAEnd,
+	inDISPLAYGROUPLIST:       decodeDisplayGroupList,
+	inDISPLAYGROUPUPDATED:    decodeDisplayGroupUpdated,
+	inDELTANEUTRALVALIDATION: decodeDeltaNeutralValidation,
 }
 
 // func (rd *msgReader) readMessage() (m *Message, err error) {
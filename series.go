@@ -0,0 +1,191 @@
+package ibgo
+
+import (
+	"sort"
+	"time"
+)
+
+// BarSeries is a time ordered run of bars of one instrument. The instrument supplies the trading and liquid
+// hours calendar and the time zone; without one, days run from midnight UTC
+type BarSeries struct {
+	Instrument *Instrument
+	Bars       []BarData
+}
+
+// calendar returns the trading or liquid hours calendar of the instrument, nil when it has none
+func (s BarSeries) calendar(useRTH bool) (*calendar, *time.Location) {
+	if s.Instrument == nil {
+		return nil, time.UTC
+	}
+	loc, err := time.LoadLocation(s.Instrument.Detail.TimeZoneID)
+	if err != nil {
+		loc = time.UTC
+	}
+	return s.Instrument.calendar(useRTH), loc
+}
+
+// Slice returns the bars starting in [start, end). The bars are shared with s
+func (s BarSeries) Slice(start time.Time, end time.Time) BarSeries {
+	i := sort.Search(len(s.Bars), func(i int) bool { return !s.Bars[i].Time.Before(start) })
+	j := sort.Search(len(s.Bars), func(i int) bool { return !s.Bars[i].Time.Before(end) })
+	if j < i {
+		j = i
+	}
+	return BarSeries{s.Instrument, s.Bars[i:j]}
+}
+
+// RTH drops the bars starting outside liquid hours, which follow the weekly pattern of the published ones
+// on days IB published none for. ErrNoSession means the instrument has no liquid hours to go by
+func (s BarSeries) RTH() (BarSeries, error) {
+	c, _ := s.calendar(true)
+	if c == nil {
+		return BarSeries{}, ErrNoSession
+	}
+	bars := make([]BarData, 0, len(s.Bars))
+	for _, bar := range s.Bars {
+		if _, in := c.find(bar.Time); in {
+			bars = append(bars, bar)
+		}
+	}
+	return BarSeries{s.Instrument, bars}, nil
+}
+
+// Resample merges bars into bars lasting d, aligned like Aggregator time bars: to the start of each session,
+// never running over its end, on every day alike. A d as long as the session, such as 24 hours, gives one bar
+// per session. Without an instrument or published hours bars are aligned to midnight throughout.
+// With useRTH bars outside liquid hours are dropped and liquid hours sessions are used; ErrNoSession means
+// the instrument has no liquid hours to go by
+func (s BarSeries) Resample(d time.Duration, useRTH bool) (BarSeries, error) {
+	c, loc := s.calendar(useRTH)
+	if useRTH && c == nil {
+		return BarSeries{}, ErrNoSession
+	}
+	bars := make([]BarData, 0)
+	var end time.Time
+	var pv float64
+	for _, bar := range s.Bars {
+		var session *Session
+		if c != nil {
+			if found, in := c.find(bar.Time); in {
+				session = &found
+			} else if useRTH {
+				continue
+			}
+		}
+		n := len(bars)
+		if n == 0 || !bar.Time.Before(end) {
+			if n > 0 && bars[n-1].Volume > 0 {
+				bars[n-1].WAP = pv / float64(bars[n-1].Volume)
+			}
+			bar.Time, end = sessionBucket(c, session, loc, bar.Time, d)
+			pv = bar.WAP * float64(bar.Volume)
+			bars = append(bars, bar)
+			continue
+		}
+		last := &bars[n-1]
+		if bar.High > last.High {
+			last.High = bar.High
+		}
+		if bar.Low < last.Low {
+			last.Low = bar.Low
+		}
+		last.Close = bar.Close
+		last.Volume += bar.Volume
+		last.TradeCount += bar.TradeCount
+		pv += bar.WAP * float64(bar.Volume)
+	}
+	if n := len(bars); n > 0 && bars[n-1].Volume > 0 {
+		bars[n-1].WAP = pv / float64(bars[n-1].Volume)
+	}
+	return BarSeries{s.Instrument, bars}, nil
+}
+
+// Clock returns the ordered union of the bar times of all series
+func Clock(series ...BarSeries) []time.Time {
+	seen := make(map[int64]time.Time)
+	for _, s := range series {
+		for _, bar := range s.Bars {
+			seen[bar.Time.UnixNano()] = bar.Time
+		}
+	}
+	clock := make([]time.Time, 0, len(seen))
+	for _, t := range seen {
+		clock = append(clock, t)
+	}
+	sort.Slice(clock, func(i, j int) bool { return clock[i].Before(clock[j]) })
+	return clock
+}
+
+// Align puts every series on clock, filling times a series has no bar at forward from its last bar with
+// a flat bar of no volume. Times before every series has started are left out, so the results line up index by index
+func Align(clock []time.Time, series ...BarSeries) []BarSeries {
+	aligned := make([]BarSeries, len(series))
+	if len(series) == 0 {
+		return aligned
+	}
+	first := 0
+	for _, s := range series {
+		if len(s.Bars) == 0 {
+			first = len(clock)
+			break
+		}
+		if i := sort.Search(len(clock), func(i int) bool { return !clock[i].Before(s.Bars[0].Time) }); i > first {
+			first = i
+		}
+	}
+	for k, s := range series {
+		bars := make([]BarData, 0, len(clock)-first)
+		j := 0
+		var last BarData
+		for i, t := range clock {
+			for j < len(s.Bars) && !s.Bars[j].Time.After(t) {
+				last = s.Bars[j]
+				j++
+			}
+			if i < first {
+				continue
+			}
+			if last.Time.Equal(t) {
+				bars = append(bars, last)
+			} else {
+				bars = append(bars, BarData{Time: t, Open: last.Close, High: last.Close, Low: last.Close, Close: last.Close, WAP: last.Close})
+			}
+		}
+		aligned[k] = BarSeries{s.Instrument, bars}
+	}
+	return aligned
+}
+
+// JoinedBar holds the bars of several series starting at the same time, in the order the series were given
+type JoinedBar struct {
+	Time time.Time
+	Bars []BarData
+}
+
+// Join pairs up the bars of all series at the times every one of them has a bar
+func Join(series ...BarSeries) []JoinedBar {
+	joined := make([]JoinedBar, 0)
+	if len(series) == 0 {
+		return joined
+	}
+	idx := make([]int, len(series))
+	for _, bar := range series[0].Bars {
+		row := JoinedBar{bar.Time, make([]BarData, len(series))}
+		row.Bars[0] = bar
+		ok := true
+		for k := 1; k < len(series) && ok; k++ {
+			bars := series[k].Bars
+			for idx[k] < len(bars) && bars[idx[k]].Time.Before(bar.Time) {
+				idx[k]++
+			}
+			ok = idx[k] < len(bars) && bars[idx[k]].Time.Equal(bar.Time)
+			if ok {
+				row.Bars[k] = bars[idx[k]]
+			}
+		}
+		if ok {
+			joined = append(joined, row)
+		}
+	}
+	return joined
+}
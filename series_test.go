@@ -0,0 +1,62 @@
+package ibgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResampleAlignsEveryDay(t *testing.T) {
+	ins, loc := weekInstrument(t)
+	at := func(day, h, m int) time.Time { return time.Date(2024, 1, day, h, m, 0, 0, loc) }
+	bar := func(t time.Time, price float64) BarData {
+		return BarData{Time: t, Open: price, High: price, Low: price, Close: price, Volume: 1, WAP: price}
+	}
+	series := BarSeries{ins, []BarData{
+		bar(at(2, 8, 0), 1),
+		bar(at(2, 9, 30), 2),
+		bar(at(2, 10, 29), 3),
+		bar(at(2, 15, 59), 4),
+		bar(at(9, 9, 30), 5),
+		bar(at(9, 10, 29), 6),
+		bar(at(9, 15, 59), 7),
+	}}
+	tests := []struct {
+		useRTH bool
+		times  []time.Time
+	}{
+		{false, []time.Time{at(2, 7, 30), at(2, 9, 30), at(2, 15, 30), at(9, 9, 30), at(9, 15, 30)}},
+		{true, []time.Time{at(2, 9, 30), at(2, 15, 30), at(9, 9, 30), at(9, 15, 30)}},
+	}
+	for _, tt := range tests {
+		got, err := series.Resample(time.Hour, tt.useRTH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Bars) != len(tt.times) {
+			t.Fatalf("useRTH %v: %v bars, want %v", tt.useRTH, len(got.Bars), len(tt.times))
+		}
+		for i, want := range tt.times {
+			if !got.Bars[i].Time.Equal(want) {
+				t.Errorf("useRTH %v: bar %v at %v, want %v", tt.useRTH, i, got.Bars[i].Time, want)
+			}
+		}
+	}
+	rth, err := series.RTH()
+	if err != nil || len(rth.Bars) != 6 {
+		t.Errorf("RTH kept %v bars, %v, want 6", len(rth.Bars), err)
+	}
+}
+
+func TestResampleWithoutHours(t *testing.T) {
+	series := BarSeries{&Instrument{}, []BarData{{Time: time.Date(2024, 1, 2, 9, 47, 0, 0, time.UTC), Volume: 1}}}
+	if _, err := series.Resample(time.Hour, true); err != ErrNoSession {
+		t.Errorf("Resample with useRTH = %v, want ErrNoSession", err)
+	}
+	if _, err := series.RTH(); err != ErrNoSession {
+		t.Errorf("RTH = %v, want ErrNoSession", err)
+	}
+	got, err := series.Resample(time.Hour, false)
+	if want := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC); err != nil || len(got.Bars) != 1 || !got.Bars[0].Time.Equal(want) {
+		t.Errorf("Resample = %v, %v, want one bar at %v", got.Bars, err, want)
+	}
+}
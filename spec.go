@@ -0,0 +1,223 @@
+package ibgo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var knownSecTypes = map[string]struct{}{
+	"STK": {}, "OPT": {}, "FUT": {}, "CONTFUT": {}, "FOP": {}, "CASH": {}, "IND": {}, "CFD": {},
+	"CRYPTO": {}, "BOND": {}, "BAG": {}, "WAR": {}, "FUND": {}, "CMDTY": {}, "NEWS": {}, "IOPT": {},
+}
+
+var knownCurrencies = map[string]struct{}{
+	"USD": {}, "EUR": {}, "GBP": {}, "JPY": {}, "CHF": {}, "CAD": {}, "AUD": {}, "NZD": {}, "HKD": {},
+	"SGD": {}, "SEK": {}, "NOK": {}, "DKK": {}, "CNH": {}, "KRW": {}, "INR": {}, "MXN": {}, "ZAR": {},
+	"PLN": {}, "CZK": {}, "HUF": {}, "ILS": {}, "TRY": {}, "RUB": {},
+}
+
+var occSymbol = regexp.MustCompile(`^([A-Z0-9.]{1,6})\s*(\d{6})([CP])(\d{8})$`)
+var fxPair = regexp.MustCompile(`^([A-Z]{3})\.([A-Z]{3})$`)
+var expiryToken = regexp.MustCompile(`^\d{6}(\d{2})?$`)
+
+// ParseContract turns a compact description into a Contract. Accepted forms include
+//
+//	AAPL STK SMART USD
+//	ES FUT 202412 GLOBEX
+//	SPY 20241220 450 C
+//	SPY   241220C00450000    (OCC option symbol)
+//	EUR.USD                  (forex pair)
+//
+// Exchange and primary exchange may be joined as SMART:ARCA, other fields are given as
+// conid=, multiplier=, localsymbol= and tradingclass=. Symbols with spaces are quoted
+func ParseContract(spec string) (con Contract, err error) {
+	spec = strings.TrimSpace(spec)
+	if m := occSymbol.FindStringSubmatch(strings.ToUpper(spec)); m != nil {
+		strike, _ := strconv.ParseInt(m[4], 10, 64)
+		return Contract{Symbol: m[1], SecType: "OPT", LastTradeDateOrContractMonth: "20" + m[2], Right: m[3],
+			Strike: float64(strike) / 1000, Exchange: "SMART", Currency: "USD", Multiplier: "100"}, nil
+	}
+	tokens, err := splitSpec(spec)
+	if err != nil {
+		return
+	}
+	if len(tokens) == 0 {
+		return con, fmt.Errorf("Empty contract spec")
+	}
+	rest := tokens
+	if !strings.Contains(tokens[0], "=") {
+		con.Symbol = strings.ToUpper(tokens[0])
+		rest = tokens[1:]
+		if m := fxPair.FindStringSubmatch(con.Symbol); m != nil {
+			con.Symbol, con.Currency, con.SecType = m[1], m[2], "CASH"
+		}
+	}
+	hasStrike := false
+	for _, tok := range rest {
+		up := strings.ToUpper(tok)
+		if kv := strings.SplitN(tok, "=", 2); len(kv) == 2 {
+			if err = setSpecField(&con, strings.ToLower(kv[0]), kv[1]); err != nil {
+				return
+			}
+			continue
+		}
+		if _, ok := knownSecTypes[up]; ok {
+			con.SecType = up
+		} else if expiryToken.MatchString(up) && con.LastTradeDateOrContractMonth == "" {
+			con.LastTradeDateOrContractMonth = up
+		} else if f, err := strconv.ParseFloat(up, 64); err == nil && !hasStrike {
+			con.Strike, hasStrike = f, true
+		} else if right := normaliseRight(up); right != "" {
+			con.Right = right
+		} else if _, ok := knownCurrencies[up]; ok && con.Currency == "" {
+			con.Currency = up
+		} else if con.Exchange == "" {
+			sl := strings.SplitN(up, ":", 2)
+			con.Exchange = sl[0]
+			if len(sl) == 2 {
+				con.PrimaryExchange = sl[1]
+			}
+		} else {
+			return con, fmt.Errorf("Unexpected token %q in contract spec", tok)
+		}
+	}
+	if con.SecType == "" {
+		switch {
+		case con.Right != "" && hasStrike:
+			con.SecType = "OPT"
+		case con.LastTradeDateOrContractMonth != "":
+			con.SecType = "FUT"
+		case con.ConID == 0:
+			con.SecType = "STK"
+		}
+	}
+	if con.Symbol != "" && con.Currency == "" {
+		con.Currency = "USD"
+	}
+	if con.Exchange == "" {
+		switch con.SecType {
+		case "STK", "OPT":
+			con.Exchange = "SMART"
+		case "CASH":
+			con.Exchange = "IDEALPRO"
+		}
+	}
+	return
+}
+
+// MustParseContract is like ParseContract but panics on error. It is meant for literals
+func MustParseContract(spec string) Contract {
+	con, err := ParseContract(spec)
+	if err != nil {
+		panic(err)
+	}
+	return con
+}
+
+func normaliseRight(s string) string {
+	switch s {
+	case "C", "CALL":
+		return "C"
+	case "P", "PUT":
+		return "P"
+	}
+	return ""
+}
+
+func setSpecField(con *Contract, key string, value string) (err error) {
+	switch key {
+	case "conid":
+		con.ConID, err = strconv.ParseInt(value, 10, 64)
+	case "multiplier", "mult":
+		con.Multiplier = value
+	case "localsymbol":
+		con.LocalSymbol = value
+	case "tradingclass":
+		con.TradingClass = value
+	case "primary":
+		con.PrimaryExchange = strings.ToUpper(value)
+	default:
+		err = fmt.Errorf("Unknown contract field %q", key)
+	}
+	return
+}
+
+func splitSpec(spec string) (tokens []string, err error) {
+	tokens = make([]string, 0)
+	var b strings.Builder
+	quoted := false
+	for _, r := range spec {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("Unterminated quote in contract spec")
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return
+}
+
+// FormatContract is the inverse of ParseContract
+func FormatContract(c Contract) string {
+	fields := make([]string, 0, 8)
+	if c.Symbol != "" {
+		fields = append(fields, quoteSpec(c.Symbol))
+	}
+	if c.SecType != "" {
+		fields = append(fields, c.SecType)
+	}
+	if c.LastTradeDateOrContractMonth != "" {
+		fields = append(fields, c.LastTradeDateOrContractMonth)
+	}
+	if c.Strike != 0 {
+		fields = append(fields, strconv.FormatFloat(c.Strike, 'f', -1, 64))
+	}
+	if c.Right != "" {
+		fields = append(fields, normaliseRight(strings.ToUpper(c.Right)))
+	}
+	if c.Exchange != "" {
+		if c.PrimaryExchange != "" {
+			fields = append(fields, c.Exchange+":"+c.PrimaryExchange)
+		} else {
+			fields = append(fields, c.Exchange)
+		}
+	} else if c.PrimaryExchange != "" {
+		fields = append(fields, "primary="+c.PrimaryExchange)
+	}
+	if c.Currency != "" {
+		fields = append(fields, c.Currency)
+	}
+	if c.Multiplier != "" {
+		fields = append(fields, "multiplier="+c.Multiplier)
+	}
+	if c.TradingClass != "" && !strings.ContainsAny(c.TradingClass, " \"") {
+		fields = append(fields, "tradingclass="+c.TradingClass)
+	}
+	if c.LocalSymbol != "" && !strings.ContainsAny(c.LocalSymbol, " \"") {
+		fields = append(fields, "localsymbol="+c.LocalSymbol)
+	}
+	if c.ConID != 0 {
+		fields = append(fields, "conid="+strconv.FormatInt(c.ConID, 10))
+	}
+	return strings.Join(fields, " ")
+}
+
+func quoteSpec(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return `"` + s + `"`
+	}
+	return s
+}
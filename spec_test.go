@@ -0,0 +1,76 @@
+package ibgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContract(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Contract
+	}{
+		{"AAPL STK SMART USD", Contract{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}},
+		{"aapl", Contract{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}},
+		{"SPY SMART:ARCA", Contract{Symbol: "SPY", SecType: "STK", Exchange: "SMART", PrimaryExchange: "ARCA", Currency: "USD"}},
+		{"ES FUT 202412 GLOBEX", Contract{Symbol: "ES", SecType: "FUT", LastTradeDateOrContractMonth: "202412", Exchange: "GLOBEX", Currency: "USD"}},
+		{"ES 202412 GLOBEX", Contract{Symbol: "ES", SecType: "FUT", LastTradeDateOrContractMonth: "202412", Exchange: "GLOBEX", Currency: "USD"}},
+		{"SPY 20241220 450 C", Contract{Symbol: "SPY", SecType: "OPT", LastTradeDateOrContractMonth: "20241220", Strike: 450, Right: "C", Exchange: "SMART", Currency: "USD"}},
+		{"SPY 20241220 450.5 put", Contract{Symbol: "SPY", SecType: "OPT", LastTradeDateOrContractMonth: "20241220", Strike: 450.5, Right: "P", Exchange: "SMART", Currency: "USD"}},
+		{"SPY   241220C00450000", Contract{Symbol: "SPY", SecType: "OPT", LastTradeDateOrContractMonth: "20241220", Strike: 450, Right: "C", Exchange: "SMART", Currency: "USD", Multiplier: "100"}},
+		{"EUR.USD", Contract{Symbol: "EUR", SecType: "CASH", Exchange: "IDEALPRO", Currency: "USD"}},
+		{"SAP STK IBIS EUR", Contract{Symbol: "SAP", SecType: "STK", Exchange: "IBIS", Currency: "EUR"}},
+		{`"BRK B" STK SMART`, Contract{Symbol: "BRK B", SecType: "STK", Exchange: "SMART", Currency: "USD"}},
+		{"conid=265598", Contract{ConID: 265598}},
+		{"ES FUT GLOBEX multiplier=50 localsymbol=ESZ4 tradingclass=ES", Contract{Symbol: "ES", SecType: "FUT", Exchange: "GLOBEX", Currency: "USD", Multiplier: "50", LocalSymbol: "ESZ4", TradingClass: "ES"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseContract(tt.spec)
+		if err != nil {
+			t.Errorf("ParseContract(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseContract(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseContractErrors(t *testing.T) {
+	for _, spec := range []string{"", "   ", `"BRK B STK`, "AAPL STK SMART ARCA", "AAPL foo=1", "conid=x"} {
+		if con, err := ParseContract(spec); err == nil {
+			t.Errorf("ParseContract(%q) = %+v, want error", spec, con)
+		}
+	}
+}
+
+func TestFormatContractRoundTrip(t *testing.T) {
+	specs := []string{
+		"AAPL STK SMART USD",
+		"SPY SMART:ARCA",
+		"ES FUT 202412 GLOBEX",
+		"SPY 20241220 450.5 P",
+		"SPY 241220C00450000",
+		"EUR.USD",
+		`"BRK B" STK SMART`,
+		"conid=265598",
+		"ES FUT GLOBEX multiplier=50 localsymbol=ESZ4 tradingclass=ES",
+		"VOD STK primary=LSE GBP conid=12345",
+	}
+	for _, spec := range specs {
+		con, err := ParseContract(spec)
+		if err != nil {
+			t.Errorf("ParseContract(%q) error: %v", spec, err)
+			continue
+		}
+		formatted := FormatContract(con)
+		again, err := ParseContract(formatted)
+		if err != nil {
+			t.Errorf("ParseContract(FormatContract(%q)) = ParseContract(%q) error: %v", spec, formatted, err)
+			continue
+		}
+		if !reflect.DeepEqual(again, con) {
+			t.Errorf("%q formats as %q which parses to %+v, want %+v", spec, formatted, again, con)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -43,30 +44,90 @@ const (
 )
 
 type BarData struct {
-	Time       string
-	Open       float64
-	High       float64
-	Low        float64
-	Close      float64
-	Volume     int64
-	TradeCount int64
+	Time       time.Time `json:"time"`
+	Open       float64   `json:"open"`
+	High       float64   `json:"high"`
+	Low        float64   `json:"low"`
+	Close      float64   `json:"close"`
+	Volume     int64     `json:"volume"`
+	WAP        float64   `json:"wap"`
+	TradeCount int64     `json:"count"`
 }
 
-func (bar *BarData) ToLineProtocol() (point string) {
-	return
+// ToLineProtocol encodes bar as an InfluxDB point. tags is an escaped tag set such as Instrument.LineTags returns
+func (bar *BarData) ToLineProtocol(measurement string, tags string) (point string) {
+	lp := newLine(measurement, tags)
+	lp.float("open", bar.Open)
+	lp.float("high", bar.High)
+	lp.float("low", bar.Low)
+	lp.float("close", bar.Close)
+	lp.int("volume", bar.Volume)
+	lp.float("wap", bar.WAP)
+	lp.int("count", bar.TradeCount)
+	return lp.end(bar.Time)
 }
+
+// BarCSVHeader names the columns of BarData.ToCSV
+var BarCSVHeader = []string{"time", "open", "high", "low", "close", "volume", "wap", "count"}
+
 func (bar *BarData) ToCSV() (record []string) {
-	record = make([]string, 7)
-	record[0] = bar.Time
+	record = make([]string, 8)
+	record[0] = bar.Time.Format(time.RFC3339)
 	record[1] = strconv.FormatFloat(bar.Open, 'g', 10, 64)
 	record[2] = strconv.FormatFloat(bar.High, 'g', 10, 64)
 	record[3] = strconv.FormatFloat(bar.Low, 'g', 10, 64)
 	record[4] = strconv.FormatFloat(bar.Close, 'g', 10, 64)
 	record[5] = strconv.FormatInt(bar.Volume, 10)
-	record[6] = strconv.FormatInt(bar.TradeCount, 10)
+	record[6] = strconv.FormatFloat(bar.WAP, 'g', 10, 64)
+	record[7] = strconv.FormatInt(bar.TradeCount, 10)
+	return
+}
+
+// ToLineProtocol encodes t as an InfluxDB point. Zero prices and sizes are left out, the exchange the tick
+// printed on is kept in the venue field
+func (t *Tick) ToLineProtocol(measurement string, tags string) (point string) {
+	lp := newLine(measurement, tags)
+	lp.nonZeroFloat("last", t.Last)
+	lp.nonZeroInt("size", t.Size)
+	lp.nonZeroFloat("bid", t.Bid)
+	lp.nonZeroFloat("ask", t.Ask)
+	lp.nonZeroInt("bidsize", t.BidSize)
+	lp.nonZeroInt("asksize", t.AskSize)
+	lp.nonZeroFloat("midpoint", t.Midpoint)
+	lp.int("mask", t.Mask)
+	if t.Exchange != "" {
+		lp.string("venue", t.Exchange)
+	}
+	if t.SpecialConditions != "" {
+		lp.string("conditions", t.SpecialConditions)
+	}
+	return lp.end(t.Time)
+}
+
+// TickCSVHeader names the columns of Tick.ToCSV
+var TickCSVHeader = []string{"time", "last", "size", "bid", "ask", "bidsize", "asksize", "midpoint", "mask", "exchange", "conditions"}
+
+func (t *Tick) ToCSV() (record []string) {
+	record = make([]string, 11)
+	record[0] = t.Time.Format(time.RFC3339Nano)
+	record[1] = strconv.FormatFloat(t.Last, 'g', 10, 64)
+	record[2] = strconv.FormatInt(t.Size, 10)
+	record[3] = strconv.FormatFloat(t.Bid, 'g', 10, 64)
+	record[4] = strconv.FormatFloat(t.Ask, 'g', 10, 64)
+	record[5] = strconv.FormatInt(t.BidSize, 10)
+	record[6] = strconv.FormatInt(t.AskSize, 10)
+	record[7] = strconv.FormatFloat(t.Midpoint, 'g', 10, 64)
+	record[8] = strconv.FormatInt(t.Mask, 10)
+	record[9] = t.Exchange
+	record[10] = t.SpecialConditions
 	return
 }
 
+type HistogramEntry struct {
+	Price float64
+	Size  int64
+}
+
 type TypedTick struct {
 	Time     time.Time
 	TickType int64
@@ -75,6 +136,19 @@ type TypedTick struct {
 	Mask     int64
 }
 
+// ToLineProtocol encodes t as an InfluxDB point with the tick type as an extra tag
+func (t *TypedTick) ToLineProtocol(measurement string, tags string) (point string) {
+	tickType := "tickType=" + strconv.FormatInt(t.TickType, 10)
+	if tags != "" {
+		tickType = tags + "," + tickType
+	}
+	lp := newLine(measurement, tickType)
+	lp.float("price", t.Price)
+	lp.int("size", t.Size)
+	lp.int("mask", t.Mask)
+	return lp.end(t.Time)
+}
+
 type TypedTickString struct {
 	Time     time.Time
 	TickType int64
@@ -103,6 +177,23 @@ type TypedTickString struct {
 // 	}
 // }
 
+// shiftTicks spreads ordered ticks sharing a second apart with SetShift, so that each keeps its own timestamp
+func shiftTicks(ticks []Tick) error {
+	var second time.Time
+	i := 1
+	for k := range ticks {
+		if ticks[k].Time.Equal(second) {
+			if err := ticks[k].SetShift(i); err != nil {
+				return err
+			}
+			i++
+		} else {
+			second, i = ticks[k].Time, 1
+		}
+	}
+	return nil
+}
+
 func (t *Tick) SetShift(i int) (err error) {
 	// fmt.Println(t.Time.UnixNano())
 	if i < 1000 {
@@ -125,3 +216,90 @@ func (t *Tick) SetSessionStart() {
 func (t *Tick) SetSessionEnd() {
 	t.Mask |= SessionEnd
 }
+
+// ToLineProtocol encodes bar as an InfluxDB point stamped with its start time
+func (bar *RealtimeBar) ToLineProtocol(measurement string, tags string) (point string) {
+	lp := newLine(measurement, tags)
+	lp.float("open", bar.Open)
+	lp.float("high", bar.High)
+	lp.float("low", bar.Low)
+	lp.float("close", bar.Close)
+	lp.int("volume", bar.Volume)
+	lp.int("wap", bar.Wap)
+	lp.float("average", bar.Average)
+	return lp.end(bar.Time)
+}
+
+// LineTags returns the conID, symbol and exchange tag set identifying ins in line protocol
+func (ins *Instrument) LineTags() string {
+	return "conID=" + strconv.FormatInt(ins.contract.ConID, 10) +
+		",symbol=" + EscapeTag(ins.contract.Symbol) +
+		",exchange=" + EscapeTag(ins.contract.Exchange)
+}
+
+type line struct {
+	b      strings.Builder
+	fields int
+}
+
+func newLine(measurement string, tags string) *line {
+	lp := &line{}
+	lp.b.WriteString(strings.NewReplacer(",", `\,`, " ", `\ `).Replace(measurement))
+	if tags != "" {
+		lp.b.WriteByte(',')
+		lp.b.WriteString(tags)
+	}
+	return lp
+}
+
+func (lp *line) key(k string) {
+	if lp.fields == 0 {
+		lp.b.WriteByte(' ')
+	} else {
+		lp.b.WriteByte(',')
+	}
+	lp.fields++
+	lp.b.WriteString(EscapeTag(k))
+	lp.b.WriteByte('=')
+}
+
+func (lp *line) float(k string, v float64) {
+	lp.key(k)
+	lp.b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (lp *line) int(k string, v int64) {
+	lp.key(k)
+	lp.b.WriteString(strconv.FormatInt(v, 10))
+	lp.b.WriteByte('i')
+}
+
+func (lp *line) nonZeroFloat(k string, v float64) {
+	if v != 0 {
+		lp.float(k, v)
+	}
+}
+
+func (lp *line) nonZeroInt(k string, v int64) {
+	if v != 0 {
+		lp.int(k, v)
+	}
+}
+
+func (lp *line) string(k string, v string) {
+	lp.key(k)
+	lp.b.WriteByte('"')
+	lp.b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v))
+	lp.b.WriteByte('"')
+}
+
+func (lp *line) end(t time.Time) string {
+	lp.b.WriteByte(' ')
+	lp.b.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+	return lp.b.String()
+}
+
+// EscapeTag escapes a tag key or value for line protocol
+func EscapeTag(s string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(s)
+}
@@ -0,0 +1,52 @@
+package ibgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToLineProtocol(t *testing.T) {
+	at := time.Unix(1700000000, 5)
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"bar", (&BarData{Time: at, Open: 1, High: 2.5, Low: 0.5, Close: 2, Volume: 10, WAP: 1.5, TradeCount: 3}).ToLineProtocol("bars", "conID=1"),
+			"bars,conID=1 open=1,high=2.5,low=0.5,close=2,volume=10i,wap=1.5,count=3i 1700000000000000005"},
+		{"bar without tags", (&BarData{Time: at}).ToLineProtocol("bars", ""),
+			"bars open=0,high=0,low=0,close=0,volume=0i,wap=0,count=0i 1700000000000000005"},
+		{"trade", (&Tick{Time: at, Last: 10.25, Size: 100, Mask: RTH, Exchange: "NYSE"}).ToLineProtocol("ticks", "conID=1"),
+			`ticks,conID=1 last=10.25,size=100i,mask=1i,venue="NYSE" 1700000000000000005`},
+		{"quote", (&Tick{Time: at, Bid: 1, Ask: 2, BidSize: 3, AskSize: 4, SpecialConditions: `a"b`}).ToLineProtocol("ticks", ""),
+			`ticks bid=1,ask=2,bidsize=3i,asksize=4i,mask=0i,conditions="a\"b" 1700000000000000005`},
+		{"typed tick", (&TypedTick{Time: at, TickType: 1, Price: 2, Size: 3}).ToLineProtocol("typed", "conID=1"),
+			"typed,conID=1,tickType=1 price=2,size=3i,mask=0i 1700000000000000005"},
+		{"typed tick without tags", (&TypedTick{Time: at, TickType: 4, Price: 2}).ToLineProtocol("typed", ""),
+			"typed,tickType=4 price=2,size=0i,mask=0i 1700000000000000005"},
+		{"escaped measurement", (&TypedTick{Time: at}).ToLineProtocol("my ticks,a", ""),
+			`my\ ticks\,a,tickType=0 price=0,size=0i,mask=0i 1700000000000000005`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%v:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestEscapeTag(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"GLOBEX", "GLOBEX"},
+		{"BRK B", `BRK\ B`},
+		{"a,b=c", `a\,b\=c`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := EscapeTag(tt.in); got != tt.want {
+			t.Errorf("EscapeTag(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
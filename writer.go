@@ -60,6 +60,28 @@ func (w *reqWriter) writeContractWithFull(c *Contract) {
 	w.writeString(c.SecIDType)
 	w.writeString(c.SecID)
 }
+func (w *reqWriter) writeComboLegs(c *Contract) {
+	if c.SecType != "BAG" {
+		return
+	}
+	w.writeInt(int64(len(c.ComboLegs)))
+	for _, leg := range c.ComboLegs {
+		w.writeInt(leg.ContractID)
+		w.writeInt(leg.Ratio)
+		w.writeString(leg.Action)
+		w.writeString(leg.Exchange)
+	}
+}
+func (w *reqWriter) writeDeltaNeutral(c *Contract) {
+	if c.DeltaNeutralContract == nil {
+		w.writeBool(false)
+		return
+	}
+	w.writeBool(true)
+	w.writeInt(c.DeltaNeutralContract.ContractID)
+	w.writeFloat(c.DeltaNeutralContract.Delta)
+	w.writeFloat(c.DeltaNeutralContract.Price)
+}
 func (w *reqWriter) send() (err error) {
 	defer func() { w.buf = w.buf[:0] }()
 	size := make([]byte, 4)